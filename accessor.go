@@ -0,0 +1,140 @@
+package dmpr
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// Accessor is implemented by models with generated accessors (see "dmpr
+// accessors" command). Mapper uses them instead of reflection for finding,
+// creating, and updating models, and for scanning rows of select queries
+// without joined relations. Models not implementing Accessor are handled
+// by reflection.
+type Accessor interface {
+	// DmprColumns returns the model's columns, without relations and
+	// read-only fields
+	DmprColumns() []string
+	// DmprScanTargets returns pointers of the model's fields, in the order
+	// of DmprColumns
+	DmprScanTargets() []interface{}
+	// DmprInsertSQL returns an INSERT query of the model into a (quoted)
+	// table, with its arguments. It returns the model's ID, if it has one.
+	DmprInsertSQL(table string) (string, []interface{})
+	// DmprUpdateSQL returns an UPDATE query of the model in a (quoted)
+	// table by its ID, with its arguments, or an empty query, if the model
+	// has no ID, or there is nothing to update.
+	DmprUpdateSQL(table string) (string, []interface{})
+}
+
+var accessorType = reflect.TypeOf((*Accessor)(nil)).Elem()
+
+// isAccessor checks whether pointers of a model type implement Accessor
+func isAccessor(t reflect.Type) bool {
+	return reflect.PtrTo(t).Implements(accessorType)
+}
+
+// accessorIndexes returns indexes of columns in an accessor's columns
+func accessorIndexes(model Accessor, columns []string) ([]int, error) {
+	known := model.DmprColumns()
+	indexes := make([]int, len(columns))
+ColumnLoop:
+	for idx, column := range columns {
+		for knownIdx, knownColumn := range known {
+			if knownColumn == column {
+				indexes[idx] = knownIdx
+				continue ColumnLoop
+			}
+		}
+		return nil, &UnknownColumnError{Model: reflect.TypeOf(model).Elem(), Column: column}
+	}
+	return indexes, nil
+}
+
+// scanAccessor scans the current row into model by its scan targets
+func scanAccessor(rows *sqlx.Rows, model Accessor) error {
+	columns, err := rows.Columns()
+	if err != nil {
+		return errors.Wrap(err, "columns")
+	}
+	indexes, err := accessorIndexes(model, columns)
+	if err != nil {
+		return err
+	}
+	targets := model.DmprScanTargets()
+	values := make([]interface{}, len(indexes))
+	for idx, targetIdx := range indexes {
+		values[idx] = targets[targetIdx]
+	}
+	return rows.Scan(values...)
+}
+
+// accessorSelect returns a SELECT query of an accessor's columns
+func accessorSelect(model Accessor, table string) string {
+	columns := model.DmprColumns()
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, quoteIdentifier(column))
+	}
+	return fmt.Sprintf("SELECT %s FROM %s", strings.Join(quoted, ", "), quoteIdentifier(table))
+}
+
+// getAccessor runs a query, and scans its first row into model. It returns
+// sql.ErrNoRows if there are no rows, just like Get.
+func (m *Mapper) getAccessor(model Accessor, query string, args ...interface{}) error {
+	rows, err := m.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	return scanAccessor(rows, model)
+}
+
+// selectAccessors runs a query, and appends its rows to a slice of models
+// (or pointers of models), which implement Accessor.
+func (m *Mapper) selectAccessors(value reflect.Value, t reflect.Type, query string, args ...interface{}) error {
+	isPtr := value.Type().Elem().Kind() == reflect.Ptr
+	rows, err := m.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		vp := reflect.New(t)
+		if err := scanAccessor(rows, vp.Interface().(Accessor)); err != nil {
+			return err
+		}
+		if isPtr {
+			value.Set(reflect.Append(value, vp))
+		} else {
+			value.Set(reflect.Append(value, vp.Elem()))
+		}
+	}
+	return rows.Err()
+}
+
+// saveAccessor runs a generated INSERT or UPDATE query, scanning its
+// returned columns (if any) into model
+func (m *Mapper) saveAccessor(model Accessor, query string, args []interface{}) error {
+	rows, err := m.Queryx(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if rows.Next() {
+		if err := scanAccessor(rows, model); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
@@ -0,0 +1,199 @@
+package dmpr
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+)
+
+// ExampleAccessorModel has accessors, as generated by "dmpr accessors"
+type ExampleAccessorModel struct {
+	ID        int64       `db:"id"`
+	Name      string      `db:"name"`
+	Extra     null.String `db:"extra,omitempty"`
+	CreatedAt null.Time   `db:"created_at,omitempty"`
+}
+
+var dmprExampleAccessorModelColumns = []string{"id", "name", "extra", "created_at"}
+
+func (m *ExampleAccessorModel) DmprColumns() []string {
+	return dmprExampleAccessorModelColumns
+}
+
+func (m *ExampleAccessorModel) DmprScanTargets() []interface{} {
+	return []interface{}{&m.ID, &m.Name, &m.Extra, &m.CreatedAt}
+}
+
+func (m *ExampleAccessorModel) DmprInsertSQL(table string) (string, []interface{}) {
+	columns := make([]string, 0, 4)
+	values := make([]string, 0, 4)
+	args := make([]interface{}, 0, 4)
+	add := func(column string, arg interface{}) {
+		args = append(args, arg)
+		columns = append(columns, column)
+		values = append(values, "$"+strconv.Itoa(len(args)))
+	}
+	add("name", m.Name)
+	if m.Extra.Valid {
+		add("extra", m.Extra)
+	}
+	if m.CreatedAt.Valid {
+		add("created_at", m.CreatedAt)
+	} else {
+		columns = append(columns, "created_at")
+		values = append(values, "NOW()")
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ") RETURNING id", args
+}
+
+func (m *ExampleAccessorModel) DmprUpdateSQL(table string) (string, []interface{}) {
+	sets := make([]string, 0, 4)
+	args := make([]interface{}, 0, 4)
+	set := func(column string, arg interface{}) {
+		args = append(args, arg)
+		sets = append(sets, column+"=$"+strconv.Itoa(len(args)))
+	}
+	set("name", m.Name)
+	if m.Extra.Valid {
+		set("extra", m.Extra)
+	}
+	if m.CreatedAt.Valid {
+		set("created_at", m.CreatedAt)
+	}
+	if len(sets) == 0 {
+		return "", nil
+	}
+	args = append(args, m.ID)
+	return "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(len(args)), args
+}
+
+func TestMapper_accessors(t *testing.T) {
+	tests := []struct {
+		name     string
+		mocks    func(sqlmock.Sqlmock)
+		run      func(*Mapper) (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{
+			name: "find",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`^SELECT id, name, extra, created_at FROM example_accessor_models WHERE id = \$1$`).
+					WithArgs(5).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "extra", "created_at"}).
+						AddRow(5, "test", nil, nil))
+			},
+			run: func(m *Mapper) (interface{}, error) {
+				model := &ExampleAccessorModel{}
+				return model, m.Find(model, 5)
+			},
+			expected: &ExampleAccessorModel{ID: 5, Name: "test"},
+		},
+		{
+			name: "find by",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`^SELECT id, name, extra, created_at FROM example_accessor_models WHERE name = \$1$`).
+					WithArgs("test").
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "extra", "created_at"}))
+			},
+			run: func(m *Mapper) (interface{}, error) {
+				model := &ExampleAccessorModel{}
+				return model, m.FindBy(model, "name", "test")
+			},
+			err: errors.New("sql: no rows in result set"),
+		},
+		{
+			name: "all",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`^SELECT id, name, extra, created_at FROM example_accessor_models$`).
+					WillReturnRows(sqlmock.NewRows([]string{"id", "name", "extra", "created_at"}).
+						AddRow(1, "first", "extra", nil).
+						AddRow(2, "second", nil, nil))
+			},
+			run: func(m *Mapper) (interface{}, error) {
+				models := []*ExampleAccessorModel{}
+				return models, m.All(&models)
+			},
+			expected: []*ExampleAccessorModel{
+				{ID: 1, Name: "first", Extra: null.StringFrom("extra")},
+				{ID: 2, Name: "second"},
+			},
+		},
+		{
+			name: "create",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`^INSERT INTO example_accessor_models \(name, extra, created_at\) VALUES \(\$1, \$2, NOW\(\)\) RETURNING id$`).
+					WithArgs("test", "extra").
+					WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+			},
+			run: func(m *Mapper) (interface{}, error) {
+				model := &ExampleAccessorModel{Name: "test", Extra: null.StringFrom("extra")}
+				return model, m.Create(model)
+			},
+			expected: &ExampleAccessorModel{ID: 5, Name: "test", Extra: null.StringFrom("extra")},
+		},
+		{
+			name: "update",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`^UPDATE example_accessor_models SET name=\$1 WHERE id=\$2$`).
+					WithArgs("test", 5).
+					WillReturnRows(sqlmock.NewRows([]string{}))
+			},
+			run: func(m *Mapper) (interface{}, error) {
+				model := &ExampleAccessorModel{ID: 5, Name: "test"}
+				return model, m.Update(model)
+			},
+			expected: &ExampleAccessorModel{ID: 5, Name: "test"},
+		},
+		{
+			name: "select query",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(`^SELECT t1\.name, t1\.id FROM example_accessor_models t1 WHERE t1\.name = :name$`).
+					WithArgs("test").
+					WillReturnRows(sqlmock.NewRows([]string{"name", "id"}).AddRow("test", 3))
+			},
+			run: func(m *Mapper) (interface{}, error) {
+				models := []ExampleAccessorModel{}
+				query, err := m.NewSelect(&models)
+				if err != nil {
+					return nil, err
+				}
+				err = query.Select("name", "id").Where(Eq("name", "test")).All()
+				return models, err
+			},
+			expected: []ExampleAccessorModel{{ID: 3, Name: "test"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mocks(mock)
+			mapper := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			result, err := tt.run(mapper)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if err == nil && !reflect.DeepEqual(tt.expected, result) {
+				t.Errorf("results don't match.\nExpected: %+v\nReceived: %+v", tt.expected, result)
+			}
+		})
+	}
+}
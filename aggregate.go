@@ -0,0 +1,131 @@
+package dmpr
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/pkg/errors"
+)
+
+// Aggregate represents an aggregate function call on a column, selected
+// with an alias. The alias is used for scanning results into destination
+// structs, and for referencing the aggregate in HAVING clauses.
+type Aggregate struct {
+	fn     string
+	column string
+	alias  string
+}
+
+// NewAggregate returns an aggregate of an SQL function on a column. Column
+// can be "*", or a column of the model, optionally qualified with a joined
+// relation's name or table alias.
+func NewAggregate(fn, column, alias string) *Aggregate {
+	return &Aggregate{fn: fn, column: column, alias: alias}
+}
+
+// Count returns a count() aggregate
+func Count(column, alias string) *Aggregate {
+	return NewAggregate("count", column, alias)
+}
+
+// Sum returns a sum() aggregate
+func Sum(column, alias string) *Aggregate {
+	return NewAggregate("sum", column, alias)
+}
+
+// Avg returns an avg() aggregate
+func Avg(column, alias string) *Aggregate {
+	return NewAggregate("avg", column, alias)
+}
+
+// Min returns a min() aggregate
+func Min(column, alias string) *Aggregate {
+	return NewAggregate("min", column, alias)
+}
+
+// Max returns a max() aggregate
+func Max(column, alias string) *Aggregate {
+	return NewAggregate("max", column, alias)
+}
+
+// expr returns the aggregate's SQL expression on the resolved column
+func (a *Aggregate) expr(column string) string {
+	return fmt.Sprintf("%s(%s)", a.fn, column)
+}
+
+// GroupBy sets columns the query groups its results by. Columns can be
+// qualified with table aliases, just like in Select.
+func (q *SelectQuery) GroupBy(columns ...string) *SelectQuery {
+	q.group = append(q.group, columns...)
+	return q
+}
+
+// Having sets HAVING clause of grouped queries, using Operator interface.
+// Operators can reference aggregates by their aliases, or model columns.
+// Calling it multiple times will yield an AND relationship among operators.
+func (q *SelectQuery) Having(op Operator) *SelectQuery {
+	if q.having != nil {
+		q.having = And(q.having, op)
+	} else {
+		q.having = op
+	}
+	return q
+}
+
+// Aggregate executes an aggregate SELECT query, scanning results into dest,
+// which can be a pointer of a struct, or a pointer of a slice of structs.
+// Dest's fields are mapped by their "db" tags to grouped columns and
+// aggregate aliases. The query selects columns set by Select, or grouped
+// columns by default, and the aggregates. Joined relations can be used in
+// filters, groups, and aggregates by their names or table aliases.
+func (q *SelectQuery) Aggregate(dest interface{}, aggregates ...*Aggregate) error {
+	query, args, err := q.aggregateSelector(aggregates)
+	if err != nil {
+		return err
+	}
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("non-nil pointer is expected as Aggregate destination")
+	}
+	if deref(value.Type()).Kind() == reflect.Slice {
+		err = q.mapper.Select(dest, query, args...)
+	} else {
+		err = q.mapper.Get(dest, query, args...)
+	}
+	return errors.Wrap(err, "Aggregate query")
+}
+
+func (q *SelectQuery) aggregateSelector(aggregates []*Aggregate) (string, []interface{}, error) {
+	t, _ := Reflect(q.model)
+	fl := q.mapper.FieldList(t)
+	table, err := q.mapper.tableName(q.model)
+	if err != nil {
+		return "", nil, err
+	}
+	joined, _, args, err := q.joins(fl)
+	if err != nil {
+		return "", nil, err
+	}
+	joined = append([]string{quoteIdentifier(table) + " t1"}, joined...)
+	columns := q.sel
+	if len(columns) < 1 {
+		columns = q.group
+	}
+	selected, err := q.resolveColumns(fl, columns)
+	if err != nil {
+		return "", nil, err
+	}
+	exprs := make(map[string]string, len(aggregates))
+	for _, agg := range aggregates {
+		column, err := q.resolveColumn(fl, agg.column)
+		if err != nil {
+			return "", nil, err
+		}
+		exprs[agg.alias] = agg.expr(column)
+		selected = append(selected, fmt.Sprintf("%s AS %s", exprs[agg.alias], quoteIdentifier(agg.alias)))
+	}
+	if len(selected) < 1 {
+		return "", nil, errors.New("nothing to select")
+	}
+	return q.render(fl, selected, joined, args, exprs)
+}
@@ -0,0 +1,155 @@
+package dmpr_test
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/dmpr"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+)
+
+type ExampleStats struct {
+	OneID int     `db:"one_id"`
+	Total int     `db:"total"`
+	Avg   float64 `db:"avg_more"`
+}
+
+type ExampleCount struct {
+	ID    int
+	Total int `db:"total"`
+}
+
+type ExampleTotal struct {
+	Total int `db:"total"`
+}
+
+func TestSelectQuery_Aggregate(t *testing.T) {
+	tests := []struct {
+		name       string
+		model      interface{}
+		prep       func(*dmpr.SelectQuery)
+		aggregates []*dmpr.Aggregate
+		dest       interface{}
+		mock       func(sqlmock.Sqlmock)
+		expected   interface{}
+		err        error
+	}{
+		{
+			name:       "grouped count",
+			model:      &[]ExampleBelongsTo{},
+			prep:       func(s *dmpr.SelectQuery) { s.GroupBy("one_id") },
+			aggregates: []*dmpr.Aggregate{dmpr.Count("*", "total"), dmpr.Avg("more_id", "avg_more")},
+			dest:       &[]ExampleStats{},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"one_id", "total", "avg_more"}).
+					AddRow(1, 3, 1.5).
+					AddRow(2, 1, 4)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.one_id, count(*) AS total, avg(t1.more_id) AS avg_more `+
+						`FROM example_belongs_toes t1 GROUP BY t1.one_id`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleStats{{OneID: 1, Total: 3, Avg: 1.5}, {OneID: 2, Total: 1, Avg: 4}},
+		},
+		{
+			name:  "filter and having",
+			model: &[]ExampleBelongsTo{},
+			prep: func(s *dmpr.SelectQuery) {
+				s.Where(dmpr.Gt("more_id", 0)).GroupBy("one_id").Having(dmpr.Ge("total", 2))
+			},
+			aggregates: []*dmpr.Aggregate{dmpr.Count("id", "total")},
+			dest:       &[]ExampleStats{},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"one_id", "total"}).
+					AddRow(1, 3)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.one_id, count(t1.id) AS total `+
+						`FROM example_belongs_toes t1 WHERE t1.more_id > :more_id GROUP BY t1.one_id HAVING count(t1.id) >= :total`,
+				))).WithArgs(0, 2).WillReturnRows(rows)
+			},
+			expected: &[]ExampleStats{{OneID: 1, Total: 3}},
+		},
+		{
+			name:       "joined relation",
+			model:      &[]ExampleHasMany{},
+			prep:       func(s *dmpr.SelectQuery) { s.Join("belongs").GroupBy("t1.id") },
+			aggregates: []*dmpr.Aggregate{dmpr.Count("t2.id", "total")},
+			dest:       &[]ExampleCount{},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "total"}).AddRow(1, 2)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, count(t2.id) AS total `+
+						`FROM example_has_manies t1 LEFT JOIN example_belongs_toes t2 ON (t1.id=t2.many_id) GROUP BY t1.id`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleCount{{ID: 1, Total: 2}},
+		},
+		{
+			name:       "single struct",
+			model:      &[]ExampleBelongsTo{},
+			aggregates: []*dmpr.Aggregate{dmpr.Sum("more_id", "total")},
+			dest:       &ExampleTotal{},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"total"}).AddRow(42)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT sum(t1.more_id) AS total FROM example_belongs_toes t1`,
+				))).WillReturnRows(rows)
+			},
+			expected: &ExampleTotal{Total: 42},
+		},
+		{
+			name:       "unknown aggregated column",
+			model:      &[]ExampleBelongsTo{},
+			aggregates: []*dmpr.Aggregate{dmpr.Max("secret", "total")},
+			dest:       &ExampleTotal{},
+			err:        errors.New(`unknown column "secret" in model dmpr_test.ExampleBelongsTo`),
+		},
+		{
+			name:       "unknown having column",
+			model:      &[]ExampleBelongsTo{},
+			prep:       func(s *dmpr.SelectQuery) { s.GroupBy("one_id").Having(dmpr.Gt("totals", 1)) },
+			aggregates: []*dmpr.Aggregate{dmpr.Count("*", "total")},
+			dest:       &[]ExampleStats{},
+			err:        errors.New(`unknown column "totals" in model dmpr_test.ExampleBelongsTo`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			mapper := dmpr.New("")
+			mapper.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
+			q, err := mapper.NewSelect(tt.model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prep != nil {
+				tt.prep(q)
+			}
+			if tt.mock != nil {
+				tt.mock(mock)
+			}
+			err = q.Aggregate(tt.dest, tt.aggregates...)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err != nil {
+				return
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.expected != nil && !reflect.DeepEqual(tt.dest, tt.expected) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, tt.dest)
+			}
+		})
+	}
+}
@@ -0,0 +1,378 @@
+package dmpr
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SaveOption is an option of Create and Update
+type SaveOption func(*saveOptions)
+
+type saveOptions struct {
+	associations []string
+}
+
+func newSaveOptions(opts []SaveOption) *saveOptions {
+	options := &saveOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+	return options
+}
+
+// WithAssociations makes Create and Update save related models referenced
+// by relation names (just like in SelectQuery.Join). "Belongs to" models
+// are saved first, and the model's reference field (relation name + "_id")
+// is set to their IDs. Then, the model itself is saved, and then "has one"
+// and "has many" models, setting their reference fields (value of the
+// "relation" tag option + "_id") to the model's ID. Polymorphic relations'
+// type columns are set too. Related models without an ID are created,
+// others are updated. Nil pointers and zero valued "belongs to" models are
+// skipped. "Many to many" relations are not supported; use Associate for
+// them.
+func WithAssociations(relations ...string) SaveOption {
+	return func(opts *saveOptions) {
+		opts.associations = append(opts.associations, relations...)
+	}
+}
+
+// saveAssociations saves model with save, and its related models in the
+// right order. It is expected to run in a transaction.
+func (m *Mapper) saveAssociations(model interface{}, relations []string, save func(interface{}) error) error {
+	typ, value := Reflect(model)
+	if value.Kind() != reflect.Struct || !value.CanAddr() {
+		return errors.New("pointer of a struct is expected for saving associations")
+	}
+	fl := m.FieldList(typ)
+	if fl == nil {
+		return errors.New("cannot get field list")
+	}
+	var belongs, hasN []FieldListItem
+	for _, relation := range relations {
+		field, err := fl.relationField(relation)
+		if err != nil {
+			return err
+		}
+		if _, ok := field.Options[OptBelongs]; ok {
+			belongs = append(belongs, field)
+			continue
+		}
+		if _, ok := field.Options[OptVia]; ok {
+			return errors.Errorf("relation %q goes through %q, save that instead", relation, field.Options[OptThrough])
+		}
+		if _, ok := field.Options[OptThrough]; ok {
+			return errors.Errorf("relation %q is many-to-many, use Associate instead", relation)
+		}
+		hasN = append(hasN, field)
+	}
+	for _, field := range belongs {
+		parent := value.FieldByIndex(field.Index)
+		if parent.Kind() == reflect.Ptr {
+			if parent.IsNil() {
+				continue
+			}
+			parent = parent.Elem()
+		}
+		if parent.IsZero() {
+			continue
+		}
+		if err := m.saveModel(parent.Addr().Interface()); err != nil {
+			return errors.Wrapf(err, "saving %q", field.Path)
+		}
+		reference := field.Path
+		if poly, ok := field.Options[OptPolymorphic]; ok {
+			reference = poly
+			if err := m.setPolymorphicType(fl, parent.Type(), field, model, poly+"_type"); err != nil {
+				return err
+			}
+		}
+		if err := m.copyID(parent.Addr().Interface(), model, reference+"_id"); err != nil {
+			return err
+		}
+	}
+	if err := save(model); err != nil {
+		return err
+	}
+	for _, field := range hasN {
+		reference, hasRelation := field.Options[OptRelation]
+		poly, hasPoly := field.Options[OptPolymorphic]
+		if !hasRelation {
+			reference = poly
+		}
+		children := value.FieldByIndex(field.Index)
+		if children.Kind() == reflect.Ptr {
+			if children.IsNil() {
+				continue
+			}
+			children = children.Elem()
+		}
+		if children.Kind() != reflect.Slice {
+			children = reflect.Append(reflect.MakeSlice(reflect.SliceOf(reflect.PtrTo(children.Type())), 0, 1), children.Addr())
+		}
+		for idx := 0; idx < children.Len(); idx++ {
+			child := children.Index(idx)
+			if child.Kind() == reflect.Ptr {
+				if child.IsNil() {
+					continue
+				}
+				child = child.Elem()
+			}
+			childPtr := child.Addr().Interface()
+			if err := m.copyID(model, childPtr, reference+"_id"); err != nil {
+				return err
+			}
+			if hasPoly && !hasRelation {
+				if err := m.setPolymorphicType(fl, typ, field, childPtr, poly+"_type"); err != nil {
+					return err
+				}
+			}
+			if err := m.saveModel(childPtr); err != nil {
+				return errors.Wrapf(err, "saving %q", field.Path)
+			}
+		}
+	}
+	return nil
+}
+
+// saveModel creates model if it has no ID yet, or updates it otherwise
+func (m *Mapper) saveModel(model interface{}) error {
+	id, ok := m.FieldMap(model)["id"]
+	if !ok {
+		return errors.New("no ID field found")
+	}
+	if isEmptyValue(id) {
+		return m.insert(model)
+	}
+	return m.update(model)
+}
+
+// copyID sets src model's ID to dst model's field named key
+func (m *Mapper) copyID(src, dst interface{}, key string) error {
+	id, ok := m.FieldMap(src)["id"]
+	if !ok {
+		return errors.New("no ID field found")
+	}
+	field, ok := m.FieldMap(dst)[key]
+	if !ok {
+		return errors.Errorf("unknown field key: %s", key)
+	}
+	return setValue(field, id)
+}
+
+// setPolymorphicType sets the type column of a polymorphic relation in dst
+// model to the type name of t.
+func (m *Mapper) setPolymorphicType(fl *FieldList, t reflect.Type, field FieldListItem, dst interface{}, key string) error {
+	polytype, err := fl.polymorphicType(deref(t), field)
+	if err != nil {
+		return err
+	}
+	target, ok := m.FieldMap(dst)[key]
+	if !ok {
+		return errors.Errorf("unknown field key: %s", key)
+	}
+	return setValue(target, reflect.ValueOf(polytype))
+}
+
+// setValue sets dst to src's value, converting it if needed. It supports
+// sql.Scanner destinations (like sql.NullInt64).
+func setValue(dst, src reflect.Value) error {
+	switch {
+	case src.Type().AssignableTo(dst.Type()):
+		dst.Set(src)
+	case src.Type().ConvertibleTo(dst.Type()) && dst.Kind() != reflect.String:
+		dst.Set(src.Convert(dst.Type()))
+	case dst.CanAddr():
+		scanner, ok := dst.Addr().Interface().(sql.Scanner)
+		if !ok {
+			return errors.Errorf("cannot set %s from %s", dst.Type(), src.Type())
+		}
+		return scanner.Scan(src.Interface())
+	default:
+		return errors.Errorf("cannot set %s from %s", dst.Type(), src.Type())
+	}
+	return nil
+}
+
+// Associate links model to others in a "many to many" relation, by
+// inserting rows into the relation's "through" table. Links already
+// existing are left alone. Model and others have to be saved already.
+func (m *Mapper) Associate(model interface{}, relation string, others ...interface{}) error {
+	link, err := m.linkTable(model, relation)
+	if err != nil {
+		return err
+	}
+	ids, err := m.otherIDs(link, others)
+	if err != nil {
+		return err
+	}
+	return m.Transaction(func(tx *Mapper) error {
+		return tx.associate(link, ids)
+	})
+}
+
+// Dissociate unlinks model from others in a "many to many" relation, by
+// deleting rows from the relation's "through" table.
+func (m *Mapper) Dissociate(model interface{}, relation string, others ...interface{}) error {
+	if len(others) == 0 {
+		return nil
+	}
+	link, err := m.linkTable(model, relation)
+	if err != nil {
+		return err
+	}
+	ids, err := m.otherIDs(link, others)
+	if err != nil {
+		return err
+	}
+	placeholders := make([]string, 0, len(ids))
+	for idx := range ids {
+		placeholders = append(placeholders, fmt.Sprintf("$%d", idx+2))
+	}
+	_, err = m.Exec(
+		fmt.Sprintf(
+			"DELETE FROM %s WHERE %s = $1 AND %s IN (%s)",
+			quoteIdentifier(link.table),
+			quoteIdentifier(link.key),
+			quoteIdentifier(link.otherKey),
+			strings.Join(placeholders, ", "),
+		),
+		append([]interface{}{link.id}, ids...)...,
+	)
+	return err
+}
+
+// ReplaceAssociations links model to others in a "many to many" relation,
+// removing all the other links of the model in the same relation.
+func (m *Mapper) ReplaceAssociations(model interface{}, relation string, others ...interface{}) error {
+	link, err := m.linkTable(model, relation)
+	if err != nil {
+		return err
+	}
+	ids, err := m.otherIDs(link, others)
+	if err != nil {
+		return err
+	}
+	return m.Transaction(func(tx *Mapper) error {
+		if err := tx.clearAssociations(link); err != nil {
+			return err
+		}
+		return tx.associate(link, ids)
+	})
+}
+
+// ClearAssociations removes all links of model in a "many to many" relation
+func (m *Mapper) ClearAssociations(model interface{}, relation string) error {
+	link, err := m.linkTable(model, relation)
+	if err != nil {
+		return err
+	}
+	return m.clearAssociations(link)
+}
+
+// linkTable describes a "many to many" relation's "through" table from the
+// perspective of a model instance
+type linkTable struct {
+	table     string
+	key       string
+	otherKey  string
+	otherType reflect.Type
+	id        interface{}
+}
+
+func (m *Mapper) linkTable(model interface{}, relation string) (*linkTable, error) {
+	typ, value := Reflect(model)
+	if value.Kind() != reflect.Struct {
+		return nil, ErrInvalidType
+	}
+	fl := m.FieldList(typ)
+	if fl == nil {
+		return nil, errors.New("cannot get field list")
+	}
+	field, err := fl.relationField(relation)
+	if err != nil {
+		return nil, err
+	}
+	relindex, hasRelIndex := field.Options[OptRelation]
+	revindex, hasRevIndex := field.Options[OptReverse]
+	through, hasThrough := field.Options[OptThrough]
+	if !hasRelIndex || !hasRevIndex || !hasThrough {
+		return nil, errors.Errorf("relation %q is not many-to-many", relation)
+	}
+	t := deref(field.Type)
+	if t.Kind() == reflect.Slice {
+		t = deref(t.Elem())
+	}
+	id, err := m.modelID(model)
+	if err != nil {
+		return nil, err
+	}
+	return &linkTable{
+		table:     through,
+		key:       relindex + "_id",
+		otherKey:  revindex + "_id",
+		otherType: t,
+		id:        id,
+	}, nil
+}
+
+func (m *Mapper) associate(link *linkTable, ids []interface{}) error {
+	table := quoteIdentifier(link.table)
+	key := quoteIdentifier(link.key)
+	otherKey := quoteIdentifier(link.otherKey)
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s, %s) SELECT $1, $2 WHERE NOT EXISTS "+
+			"(SELECT 1 FROM %s WHERE %s = $1 AND %s = $2)",
+		table, key, otherKey,
+		table, key, otherKey,
+	)
+	for _, id := range ids {
+		if _, err := m.Exec(query, link.id, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *Mapper) clearAssociations(link *linkTable) error {
+	_, err := m.Exec(
+		fmt.Sprintf(
+			"DELETE FROM %s WHERE %s = $1",
+			quoteIdentifier(link.table),
+			quoteIdentifier(link.key),
+		),
+		link.id,
+	)
+	return err
+}
+
+// otherIDs returns IDs of models on the other end of a link table
+func (m *Mapper) otherIDs(link *linkTable, others []interface{}) ([]interface{}, error) {
+	ids := make([]interface{}, 0, len(others))
+	for _, other := range others {
+		if t := modelType(reflect.TypeOf(other)); t != link.otherType {
+			return nil, errors.Errorf("cannot associate %v, expected %s", t, link.otherType)
+		}
+		id, err := m.modelID(other)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// modelID returns the ID of a saved model
+func (m *Mapper) modelID(model interface{}) (interface{}, error) {
+	id, ok := m.FieldMap(model)["id"]
+	if !ok {
+		return nil, errors.New("no ID field found")
+	}
+	if isEmptyValue(id) {
+		return nil, errors.Errorf("%s has no ID", modelType(reflect.TypeOf(model)))
+	}
+	return id.Interface(), nil
+}
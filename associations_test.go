@@ -0,0 +1,147 @@
+package dmpr
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type ExampleUser struct {
+	ID     int
+	Name   string
+	Groups []*ExampleGroup `db:"groups,relation=user,reverse=group,through=user_groups"`
+}
+
+type ExampleGroup struct {
+	ID   int
+	Name string
+}
+
+func TestMapper_Associations(t *testing.T) {
+	insert := "^INSERT INTO user_groups \\(user_id, group_id\\) SELECT \\$1, \\$2 WHERE NOT EXISTS " +
+		"\\(SELECT 1 FROM user_groups WHERE user_id = \\$1 AND group_id = \\$2\\)$"
+	tests := []struct {
+		name  string
+		mocks []func(sqlmock.Sqlmock)
+		call  func(*Mapper) error
+		err   error
+	}{
+		{
+			name: "associate",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectExec(insert).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectExec(insert).WithArgs(1, 3).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				},
+			},
+			call: func(m *Mapper) error {
+				return m.Associate(&ExampleUser{ID: 1}, "groups", &ExampleGroup{ID: 2}, ExampleGroup{ID: 3})
+			},
+		},
+		{
+			name: "associate unsaved",
+			call: func(m *Mapper) error {
+				return m.Associate(&ExampleUser{ID: 1}, "groups", &ExampleGroup{Name: "new"})
+			},
+			err: errors.New("dmpr.ExampleGroup has no ID"),
+		},
+		{
+			name: "associate wrong type",
+			call: func(m *Mapper) error {
+				return m.Associate(&ExampleUser{ID: 1}, "groups", &ExampleUser{ID: 2})
+			},
+			err: errors.New("cannot associate dmpr.ExampleUser, expected dmpr.ExampleGroup"),
+		},
+		{
+			name: "not many to many",
+			call: func(m *Mapper) error {
+				return m.Associate(&ExampleAuthor{ID: 1}, "posts", &ExamplePost{ID: 2})
+			},
+			err: errors.New(`relation "posts" is not many-to-many`),
+		},
+		{
+			name: "dissociate",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectExec("^DELETE FROM user_groups WHERE user_id = \\$1 AND group_id IN \\(\\$2, \\$3\\)$").
+						WithArgs(1, 2, 3).WillReturnResult(sqlmock.NewResult(0, 2))
+				},
+			},
+			call: func(m *Mapper) error {
+				return m.Dissociate(&ExampleUser{ID: 1}, "groups", &ExampleGroup{ID: 2}, &ExampleGroup{ID: 3})
+			},
+		},
+		{
+			name: "clear",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectExec("^DELETE FROM user_groups WHERE user_id = \\$1$").
+						WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+				},
+			},
+			call: func(m *Mapper) error {
+				return m.ClearAssociations(&ExampleUser{ID: 1}, "groups")
+			},
+		},
+		{
+			name: "replace",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectExec("^DELETE FROM user_groups WHERE user_id = \\$1$").
+						WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+					mock.ExpectExec(insert).WithArgs(1, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+					mock.ExpectCommit()
+				},
+			},
+			call: func(m *Mapper) error {
+				return m.ReplaceAssociations(&ExampleUser{ID: 1}, "groups", &ExampleGroup{ID: 4})
+			},
+		},
+		{
+			name: "replace rollback",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectExec("^DELETE FROM user_groups WHERE user_id = \\$1$").
+						WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 2))
+					mock.ExpectExec(insert).WithArgs(1, 4).WillReturnError(errors.New("foreign key violation"))
+					mock.ExpectRollback()
+				},
+			},
+			call: func(m *Mapper) error {
+				return m.ReplaceAssociations(&ExampleUser{ID: 1}, "groups", &ExampleGroup{ID: 4})
+			},
+			err: errors.New("foreign key violation"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			for _, item := range tt.mocks {
+				item(mock)
+			}
+			mapper := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			err = tt.call(mapper)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/julian7/dmpr"
+	"github.com/pkg/errors"
+)
+
+// accessorModel is a struct accessors are generated for
+type accessorModel struct {
+	name   string
+	fields []accessorField
+}
+
+// accessorField is a column field of an accessorModel
+type accessorField struct {
+	name   string
+	column string
+	// nonEmpty is a format string of a Go expression checking whether an
+	// "omitempty" field is not empty. It is blank for fields, which are
+	// never omitted.
+	nonEmpty string
+}
+
+// skippedOptions are tag options of fields, which are not columns of the
+// model's table
+var skippedOptions = []string{
+	dmpr.OptBelongs,
+	dmpr.OptRelation,
+	dmpr.OptPolymorphic,
+	dmpr.OptVia,
+	dmpr.OptReadOnly,
+}
+
+// parseModels reads struct types of a Go package in dir, which have at
+// least one field with a "db" tag, or which are listed in types. Test files
+// and the output file are skipped.
+func parseModels(dir, output string, types []string) (string, []*accessorModel, error) {
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go") && info.Name() != filepath.Base(output)
+	}, 0)
+	if err != nil {
+		return "", nil, errors.Wrap(err, "parsing package")
+	}
+	if len(pkgs) != 1 {
+		return "", nil, errors.Errorf("expected a single package in %s, found %d", dir, len(pkgs))
+	}
+	requested := map[string]bool{}
+	for _, name := range types {
+		requested[name] = true
+	}
+	var pkgName string
+	var models []*accessorModel
+	for name, pkg := range pkgs {
+		pkgName = name
+		for _, file := range pkg.Files {
+			for _, decl := range file.Decls {
+				gen, ok := decl.(*ast.GenDecl)
+				if !ok || gen.Tok != token.TYPE {
+					continue
+				}
+				for _, spec := range gen.Specs {
+					typeSpec := spec.(*ast.TypeSpec)
+					st, ok := typeSpec.Type.(*ast.StructType)
+					if !ok || len(types) > 0 && !requested[typeSpec.Name.Name] {
+						continue
+					}
+					model, tagged, err := parseModel(typeSpec.Name.Name, st)
+					if err != nil {
+						if len(types) > 0 {
+							return "", nil, err
+						}
+						continue
+					}
+					if tagged || len(types) > 0 {
+						models = append(models, model)
+					}
+					delete(requested, typeSpec.Name.Name)
+				}
+			}
+		}
+	}
+	for name := range requested {
+		return "", nil, errors.Errorf("type %s not found", name)
+	}
+	sort.Slice(models, func(i, j int) bool { return models[i].name < models[j].name })
+	return pkgName, models, nil
+}
+
+// parseModel reads column fields of a struct, and whether it has any "db"
+// tags.
+func parseModel(name string, st *ast.StructType) (*accessorModel, bool, error) {
+	model := &accessorModel{name: name}
+	tagged := false
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			return nil, false, errors.Errorf("%s: embedded fields are not supported", name)
+		}
+		var tag reflect.StructTag
+		if field.Tag != nil {
+			unquoted, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				return nil, false, errors.Wrapf(err, "%s: invalid tag", name)
+			}
+			tag = reflect.StructTag(unquoted)
+		}
+		dbTag, ok := tag.Lookup("db")
+		if ok {
+			tagged = true
+		}
+		if dbTag == "-" {
+			continue
+		}
+		parts := strings.Split(dbTag, ",")
+		opts := map[string]bool{}
+		for _, opt := range parts[1:] {
+			opts[strings.SplitN(opt, "=", 2)[0]] = true
+		}
+		if hasAnyOption(opts, skippedOptions) {
+			continue
+		}
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			item := accessorField{name: ident.Name, column: parts[0]}
+			if item.column == "" {
+				item.column = strings.ToLower(ident.Name)
+			}
+			if opts["omitempty"] {
+				item.nonEmpty = nonEmptyCheck(field.Type)
+			}
+			model.fields = append(model.fields, item)
+		}
+	}
+	return model, tagged, nil
+}
+
+func hasAnyOption(opts map[string]bool, names []string) bool {
+	for _, name := range names {
+		if opts[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// nonEmptyCheck returns the format string of an expression checking whether
+// a field of a type is not empty, following dmpr.IsEmpty. Types, which
+// cannot be decided by their declaration, are checked by dmpr.IsEmpty at
+// run time.
+func nonEmptyCheck(typ ast.Expr) string {
+	switch t := typ.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64",
+			"uintptr", "float32", "float64", "byte", "rune":
+			return "%s != 0"
+		case "string":
+			return `%s != ""`
+		case "bool":
+			return "%s"
+		}
+	case *ast.StarExpr, *ast.InterfaceType:
+		return "%s != nil"
+	case *ast.MapType:
+		return "len(%s) != 0"
+	case *ast.ArrayType:
+		if t.Len == nil {
+			return "len(%s) != 0"
+		}
+	case *ast.SelectorExpr:
+		pkg, ok := t.X.(*ast.Ident)
+		if !ok {
+			break
+		}
+		switch {
+		case pkg.Name == "time" && t.Sel.Name == "Time":
+			return ""
+		case pkg.Name == "null", pkg.Name == "sql" && strings.HasPrefix(t.Sel.Name, "Null"):
+			return "%s.Valid"
+		}
+	}
+	return "!dmpr.IsEmpty(%s)"
+}
+
+// column returns the named column field of a model
+func (m *accessorModel) column(name string) *accessorField {
+	for idx := range m.fields {
+		if m.fields[idx].column == name {
+			return &m.fields[idx]
+		}
+	}
+	return nil
+}
+
+// dynamic checks whether a model has "omitempty" fields, making its
+// INSERT and UPDATE queries depend on field values
+func (m *accessorModel) dynamic() bool {
+	for _, field := range m.fields {
+		if field.column != "id" && field.nonEmpty != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// generateAccessors renders Go source of accessors of models
+func generateAccessors(pkg string, models []*accessorModel) ([]byte, error) {
+	imports := map[string]bool{}
+	var body bytes.Buffer
+	for _, m := range models {
+		writeAccessors(&body, m)
+		if m.dynamic() {
+			imports["strconv"] = true
+			imports["strings"] = true
+		}
+	}
+	if bytes.Contains(body.Bytes(), []byte("dmpr.IsEmpty(")) {
+		imports["github.com/julian7/dmpr"] = true
+	}
+	return formatSource("accessors", pkg, imports, body.Bytes())
+}
+
+// writeAccessors renders dmpr.Accessor methods of a model
+func writeAccessors(w *bytes.Buffer, m *accessorModel) {
+	columnsVar := "dmpr" + m.name + "Columns"
+	columns := make([]string, 0, len(m.fields))
+	targets := make([]string, 0, len(m.fields))
+	for _, field := range m.fields {
+		columns = append(columns, strconv.Quote(field.column))
+		targets = append(targets, "&m."+field.name)
+	}
+	fmt.Fprintf(w, "\nvar %s = []string{%s}\n", columnsVar, strings.Join(columns, ", "))
+	fmt.Fprintf(w, "\n// DmprColumns returns the columns of %s\n", m.name)
+	fmt.Fprintf(w, "func (m *%s) DmprColumns() []string {\n\treturn %s\n}\n", m.name, columnsVar)
+	fmt.Fprintf(w, "\n// DmprScanTargets returns pointers of %s fields in the order of its columns\n", m.name)
+	fmt.Fprintf(
+		w,
+		"func (m *%s) DmprScanTargets() []interface{} {\n\treturn []interface{}{%s}\n}\n",
+		m.name,
+		strings.Join(targets, ", "),
+	)
+	fmt.Fprintf(w, "\n// DmprInsertSQL returns an INSERT query of %s\n", m.name)
+	fmt.Fprintf(w, "func (m *%s) DmprInsertSQL(table string) (string, []interface{}) {\n", m.name)
+	if m.dynamic() {
+		writeDynamicInsert(w, m)
+	} else {
+		writeStaticInsert(w, m)
+	}
+	w.WriteString("}\n")
+	fmt.Fprintf(w, "\n// DmprUpdateSQL returns an UPDATE query of %s by its ID\n", m.name)
+	fmt.Fprintf(w, "func (m *%s) DmprUpdateSQL(table string) (string, []interface{}) {\n", m.name)
+	switch {
+	case m.column("id") == nil || len(m.fields) < 2:
+		w.WriteString("\treturn \"\", nil\n")
+	case m.dynamic():
+		writeDynamicUpdate(w, m)
+	default:
+		writeStaticUpdate(w, m)
+	}
+	w.WriteString("}\n")
+}
+
+// insertReturning returns the RETURNING clause of a model's INSERT query
+func (m *accessorModel) insertReturning() string {
+	if m.column("id") != nil {
+		return " RETURNING id"
+	}
+	return ""
+}
+
+// updateReturning returns the RETURNING clause of a model's UPDATE query
+func (m *accessorModel) updateReturning() string {
+	if m.column("updated_at") != nil {
+		return " RETURNING updated_at"
+	}
+	return ""
+}
+
+func quoteColumn(column string) string {
+	return dmpr.DefaultDialect.QuoteIdentifier(column)
+}
+
+func writeStaticInsert(w *bytes.Buffer, m *accessorModel) {
+	var columns, values, args []string
+	for _, field := range m.fields {
+		if field.column == "id" {
+			continue
+		}
+		columns = append(columns, quoteColumn(field.column))
+		args = append(args, "m."+field.name)
+		values = append(values, "$"+strconv.Itoa(len(args)))
+	}
+	query := " DEFAULT VALUES"
+	if len(columns) > 0 {
+		query = fmt.Sprintf(" (%s) VALUES (%s)", strings.Join(columns, ", "), strings.Join(values, ", "))
+	}
+	fmt.Fprintf(
+		w,
+		"\treturn \"INSERT INTO \" + table + %q, []interface{}{%s}\n",
+		query+m.insertReturning(),
+		strings.Join(args, ", "),
+	)
+}
+
+func writeDynamicInsert(w *bytes.Buffer, m *accessorModel) {
+	fmt.Fprintf(w, "\tcolumns := make([]string, 0, %d)\n", len(m.fields))
+	fmt.Fprintf(w, "\tvalues := make([]string, 0, %d)\n", len(m.fields))
+	fmt.Fprintf(w, "\targs := make([]interface{}, 0, %d)\n", len(m.fields))
+	w.WriteString("\tadd := func(column string, arg interface{}) {\n" +
+		"\t\targs = append(args, arg)\n" +
+		"\t\tcolumns = append(columns, column)\n" +
+		"\t\tvalues = append(values, \"$\"+strconv.Itoa(len(args)))\n" +
+		"\t}\n")
+	canBeEmpty := true
+	for _, field := range m.fields {
+		if field.column == "id" {
+			continue
+		}
+		value := "m." + field.name
+		add := fmt.Sprintf("add(%q, %s)\n", quoteColumn(field.column), value)
+		switch {
+		case field.nonEmpty == "":
+			canBeEmpty = false
+			w.WriteString("\t" + add)
+		case field.column == "created_at":
+			canBeEmpty = false
+			fmt.Fprintf(w, "\tif "+field.nonEmpty+" {\n\t\t%s\t} else {\n", value, add)
+			fmt.Fprintf(w, "\t\tcolumns = append(columns, %q)\n", quoteColumn(field.column))
+			w.WriteString("\t\tvalues = append(values, \"NOW()\")\n\t}\n")
+		default:
+			fmt.Fprintf(w, "\tif "+field.nonEmpty+" {\n\t\t%s\t}\n", value, add)
+		}
+	}
+	if canBeEmpty {
+		fmt.Fprintf(
+			w,
+			"\tif len(columns) == 0 {\n\t\treturn \"INSERT INTO \" + table + \" DEFAULT VALUES%s\", args\n\t}\n",
+			m.insertReturning(),
+		)
+	}
+	fmt.Fprintf(
+		w,
+		"\treturn \"INSERT INTO \" + table + \" (\" + strings.Join(columns, \", \") + \") VALUES (\" + "+
+			"strings.Join(values, \", \") + \")%s\", args\n",
+		m.insertReturning(),
+	)
+}
+
+func writeStaticUpdate(w *bytes.Buffer, m *accessorModel) {
+	var sets, args []string
+	for _, field := range m.fields {
+		if field.column == "id" {
+			continue
+		}
+		args = append(args, "m."+field.name)
+		sets = append(sets, quoteColumn(field.column)+"=$"+strconv.Itoa(len(args)))
+	}
+	args = append(args, "m."+m.column("id").name)
+	fmt.Fprintf(
+		w,
+		"\treturn \"UPDATE \" + table + %q, []interface{}{%s}\n",
+		fmt.Sprintf(" SET %s WHERE id=$%d%s", strings.Join(sets, ", "), len(args), m.updateReturning()),
+		strings.Join(args, ", "),
+	)
+}
+
+func writeDynamicUpdate(w *bytes.Buffer, m *accessorModel) {
+	fmt.Fprintf(w, "\tsets := make([]string, 0, %d)\n", len(m.fields))
+	fmt.Fprintf(w, "\targs := make([]interface{}, 0, %d)\n", len(m.fields))
+	w.WriteString("\tset := func(column string, arg interface{}) {\n" +
+		"\t\targs = append(args, arg)\n" +
+		"\t\tsets = append(sets, column+\"=$\"+strconv.Itoa(len(args)))\n" +
+		"\t}\n")
+	for _, field := range m.fields {
+		if field.column == "id" {
+			continue
+		}
+		value := "m." + field.name
+		set := fmt.Sprintf("set(%q, %s)\n", quoteColumn(field.column), value)
+		switch {
+		case field.nonEmpty == "":
+			w.WriteString("\t" + set)
+		case field.column == "updated_at":
+			fmt.Fprintf(w, "\tif "+field.nonEmpty+" {\n\t\t%s\t} else {\n", value, set)
+			fmt.Fprintf(w, "\t\tsets = append(sets, %q)\n\t}\n", quoteColumn(field.column)+"=NOW()")
+		default:
+			fmt.Fprintf(w, "\tif "+field.nonEmpty+" {\n\t\t%s\t}\n", value, set)
+		}
+	}
+	w.WriteString("\tif len(sets) == 0 {\n\t\treturn \"\", nil\n\t}\n")
+	fmt.Fprintf(w, "\targs = append(args, m.%s)\n", m.column("id").name)
+	returning := ""
+	if suffix := m.updateReturning(); suffix != "" {
+		returning = fmt.Sprintf(" + %q", suffix)
+	}
+	fmt.Fprintf(
+		w,
+		"\treturn \"UPDATE \" + table + \" SET \" + strings.Join(sets, \", \") + \" WHERE id=$\" + "+
+			"strconv.Itoa(len(args))%s, args\n",
+		returning,
+	)
+}
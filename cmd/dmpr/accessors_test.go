@@ -0,0 +1,266 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+)
+
+const accessorModels = `package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+type Status string
+
+type User struct {
+	ID        int64       ` + "`" + `db:"id"` + "`" + `
+	Name      string      ` + "`" + `db:"name"` + "`" + `
+	Email     null.String ` + "`" + `db:"email,omitempty"` + "`" + `
+	Status    Status      ` + "`" + `db:"status,omitempty"` + "`" + `
+	CreatedAt null.Time   ` + "`" + `db:"created_at,omitempty"` + "`" + `
+	UpdatedAt null.Time   ` + "`" + `db:"updated_at,omitempty"` + "`" + `
+	Posts     []*Post     ` + "`" + `db:"posts,relation=user"` + "`" + `
+	Count     int         ` + "`" + `db:"posts_count,readonly"` + "`" + `
+	secret    string
+}
+
+type Post struct {
+	ID     int64     ` + "`" + `db:"id"` + "`" + `
+	UserID int64     ` + "`" + `db:"user_id"` + "`" + `
+	Title  string    ` + "`" + `db:"title"` + "`" + `
+	User   *User     ` + "`" + `db:"user,belongs"` + "`" + `
+	At     time.Time ` + "`" + `db:"at,omitempty"` + "`" + `
+	Order  int
+}
+
+type Tag struct {
+	ID    int64       ` + "`" + `db:"id"` + "`" + `
+	Label null.String ` + "`" + `db:"label,omitempty"` + "`" + `
+	Color string      ` + "`" + `db:"color,omitempty"` + "`" + `
+}
+
+type notModel struct{ A int }
+`
+
+const expectedAccessors = `// Code generated by dmpr accessors. DO NOT EDIT.
+
+package models
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/julian7/dmpr"
+)
+
+var dmprPostColumns = []string{"id", "user_id", "title", "at", "order"}
+
+// DmprColumns returns the columns of Post
+func (m *Post) DmprColumns() []string {
+	return dmprPostColumns
+}
+
+// DmprScanTargets returns pointers of Post fields in the order of its columns
+func (m *Post) DmprScanTargets() []interface{} {
+	return []interface{}{&m.ID, &m.UserID, &m.Title, &m.At, &m.Order}
+}
+
+// DmprInsertSQL returns an INSERT query of Post
+func (m *Post) DmprInsertSQL(table string) (string, []interface{}) {
+	return "INSERT INTO " + table + " (user_id, title, at, \"order\") VALUES ($1, $2, $3, $4) RETURNING id", []interface{}{m.UserID, m.Title, m.At, m.Order}
+}
+
+// DmprUpdateSQL returns an UPDATE query of Post by its ID
+func (m *Post) DmprUpdateSQL(table string) (string, []interface{}) {
+	return "UPDATE " + table + " SET user_id=$1, title=$2, at=$3, \"order\"=$4 WHERE id=$5", []interface{}{m.UserID, m.Title, m.At, m.Order, m.ID}
+}
+
+var dmprTagColumns = []string{"id", "label", "color"}
+
+// DmprColumns returns the columns of Tag
+func (m *Tag) DmprColumns() []string {
+	return dmprTagColumns
+}
+
+// DmprScanTargets returns pointers of Tag fields in the order of its columns
+func (m *Tag) DmprScanTargets() []interface{} {
+	return []interface{}{&m.ID, &m.Label, &m.Color}
+}
+
+// DmprInsertSQL returns an INSERT query of Tag
+func (m *Tag) DmprInsertSQL(table string) (string, []interface{}) {
+	columns := make([]string, 0, 3)
+	values := make([]string, 0, 3)
+	args := make([]interface{}, 0, 3)
+	add := func(column string, arg interface{}) {
+		args = append(args, arg)
+		columns = append(columns, column)
+		values = append(values, "$"+strconv.Itoa(len(args)))
+	}
+	if m.Label.Valid {
+		add("label", m.Label)
+	}
+	if m.Color != "" {
+		add("color", m.Color)
+	}
+	if len(columns) == 0 {
+		return "INSERT INTO " + table + " DEFAULT VALUES RETURNING id", args
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ") RETURNING id", args
+}
+
+// DmprUpdateSQL returns an UPDATE query of Tag by its ID
+func (m *Tag) DmprUpdateSQL(table string) (string, []interface{}) {
+	sets := make([]string, 0, 3)
+	args := make([]interface{}, 0, 3)
+	set := func(column string, arg interface{}) {
+		args = append(args, arg)
+		sets = append(sets, column+"=$"+strconv.Itoa(len(args)))
+	}
+	if m.Label.Valid {
+		set("label", m.Label)
+	}
+	if m.Color != "" {
+		set("color", m.Color)
+	}
+	if len(sets) == 0 {
+		return "", nil
+	}
+	args = append(args, m.ID)
+	return "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(len(args)), args
+}
+
+var dmprUserColumns = []string{"id", "name", "email", "status", "created_at", "updated_at"}
+
+// DmprColumns returns the columns of User
+func (m *User) DmprColumns() []string {
+	return dmprUserColumns
+}
+
+// DmprScanTargets returns pointers of User fields in the order of its columns
+func (m *User) DmprScanTargets() []interface{} {
+	return []interface{}{&m.ID, &m.Name, &m.Email, &m.Status, &m.CreatedAt, &m.UpdatedAt}
+}
+
+// DmprInsertSQL returns an INSERT query of User
+func (m *User) DmprInsertSQL(table string) (string, []interface{}) {
+	columns := make([]string, 0, 6)
+	values := make([]string, 0, 6)
+	args := make([]interface{}, 0, 6)
+	add := func(column string, arg interface{}) {
+		args = append(args, arg)
+		columns = append(columns, column)
+		values = append(values, "$"+strconv.Itoa(len(args)))
+	}
+	add("name", m.Name)
+	if m.Email.Valid {
+		add("email", m.Email)
+	}
+	if !dmpr.IsEmpty(m.Status) {
+		add("status", m.Status)
+	}
+	if m.CreatedAt.Valid {
+		add("created_at", m.CreatedAt)
+	} else {
+		columns = append(columns, "created_at")
+		values = append(values, "NOW()")
+	}
+	if m.UpdatedAt.Valid {
+		add("updated_at", m.UpdatedAt)
+	}
+	return "INSERT INTO " + table + " (" + strings.Join(columns, ", ") + ") VALUES (" + strings.Join(values, ", ") + ") RETURNING id", args
+}
+
+// DmprUpdateSQL returns an UPDATE query of User by its ID
+func (m *User) DmprUpdateSQL(table string) (string, []interface{}) {
+	sets := make([]string, 0, 6)
+	args := make([]interface{}, 0, 6)
+	set := func(column string, arg interface{}) {
+		args = append(args, arg)
+		sets = append(sets, column+"=$"+strconv.Itoa(len(args)))
+	}
+	set("name", m.Name)
+	if m.Email.Valid {
+		set("email", m.Email)
+	}
+	if !dmpr.IsEmpty(m.Status) {
+		set("status", m.Status)
+	}
+	if m.CreatedAt.Valid {
+		set("created_at", m.CreatedAt)
+	}
+	if m.UpdatedAt.Valid {
+		set("updated_at", m.UpdatedAt)
+	} else {
+		sets = append(sets, "updated_at=NOW()")
+	}
+	if len(sets) == 0 {
+		return "", nil
+	}
+	args = append(args, m.ID)
+	return "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE id=$" + strconv.Itoa(len(args)) + " RETURNING updated_at", args
+}
+`
+
+func writeModels(t *testing.T) string {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "models.go"), []byte(accessorModels), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestGenerateAccessors(t *testing.T) {
+	dir := writeModels(t)
+	pkg, models, err := parseModels(dir, filepath.Join(dir, "dmpr_accessors.go"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := generateAccessors(pkg, models)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != expectedAccessors {
+		t.Errorf("generated code doesn't match.\nExpected:\n%s\nReceived:\n%s", expectedAccessors, src)
+	}
+}
+
+func TestParseModels_types(t *testing.T) {
+	tests := []struct {
+		name     string
+		types    []string
+		expected []string
+		err      error
+	}{
+		{name: "selected", types: []string{"Post"}, expected: []string{"Post"}},
+		{name: "untagged", types: []string{"notModel", "User"}, expected: []string{"User", "notModel"}},
+		{name: "unknown", types: []string{"Comment"}, err: errors.New("type Comment not found")},
+	}
+	dir := writeModels(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, models, err := parseModels(dir, "dmpr_accessors.go", tt.types)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err != nil {
+				return
+			}
+			names := []string{}
+			for _, model := range models {
+				names = append(names, model.name)
+			}
+			if !reflect.DeepEqual(tt.expected, names) {
+				t.Errorf("models don't match.\nExpected: %v\nReceived: %v", tt.expected, names)
+			}
+		})
+	}
+}
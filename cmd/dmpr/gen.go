@@ -0,0 +1,329 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/gobuffalo/flect"
+	"github.com/julian7/dmpr"
+	"github.com/pkg/errors"
+)
+
+// schemaTable is a table of the introspected database schema
+type schemaTable struct {
+	name        string
+	columns     []schemaColumn
+	foreignKeys []foreignKey
+}
+
+// schemaColumn is a column of a table
+type schemaColumn struct {
+	name     string
+	dataType string
+	nullable bool
+}
+
+// foreignKey is a single column foreign key of a table
+type foreignKey struct {
+	column    string
+	refTable  string
+	refColumn string
+}
+
+// structField is a field of a generated struct
+type structField struct {
+	name string
+	typ  string
+	tag  string
+}
+
+// model is a generated struct of a table
+type model struct {
+	table  *schemaTable
+	name   string
+	fields []structField
+}
+
+// introspect reads tables, columns, and foreign keys of a database schema
+func introspect(m *dmpr.Mapper, schema string) ([]*schemaTable, error) {
+	var tableRows []struct {
+		Name string `db:"table_name"`
+	}
+	err := m.Select(
+		&tableRows,
+		"SELECT table_name FROM information_schema.tables "+
+			"WHERE table_schema = $1 AND table_type = 'BASE TABLE' ORDER BY table_name",
+		schema,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying tables")
+	}
+	tables := make([]*schemaTable, 0, len(tableRows))
+	byName := map[string]*schemaTable{}
+	for _, row := range tableRows {
+		table := &schemaTable{name: row.Name}
+		tables = append(tables, table)
+		byName[row.Name] = table
+	}
+	var columnRows []struct {
+		Table    string `db:"table_name"`
+		Name     string `db:"column_name"`
+		DataType string `db:"data_type"`
+		Nullable string `db:"is_nullable"`
+	}
+	err = m.Select(
+		&columnRows,
+		"SELECT table_name, column_name, data_type, is_nullable FROM information_schema.columns "+
+			"WHERE table_schema = $1 ORDER BY table_name, ordinal_position",
+		schema,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying columns")
+	}
+	for _, row := range columnRows {
+		if table, ok := byName[row.Table]; ok {
+			table.columns = append(table.columns, schemaColumn{
+				name:     row.Name,
+				dataType: row.DataType,
+				nullable: row.Nullable == "YES",
+			})
+		}
+	}
+	// constraint names are unique per table only, so foreign keys are read
+	// from pg_constraint by table OIDs. Multi-column keys are skipped.
+	var keyRows []struct {
+		Table     string `db:"table_name"`
+		Column    string `db:"column_name"`
+		RefTable  string `db:"foreign_table_name"`
+		RefColumn string `db:"foreign_column_name"`
+	}
+	err = m.Select(
+		&keyRows,
+		"SELECT cl.relname AS table_name, att.attname AS column_name, "+
+			"fcl.relname AS foreign_table_name, fatt.attname AS foreign_column_name "+
+			"FROM pg_constraint con "+
+			"JOIN pg_class cl ON (cl.oid = con.conrelid) "+
+			"JOIN pg_namespace ns ON (ns.oid = cl.relnamespace) "+
+			"JOIN pg_class fcl ON (fcl.oid = con.confrelid) "+
+			"JOIN pg_attribute att ON (att.attrelid = con.conrelid AND att.attnum = con.conkey[1]) "+
+			"JOIN pg_attribute fatt ON (fatt.attrelid = con.confrelid AND fatt.attnum = con.confkey[1]) "+
+			"WHERE con.contype = 'f' AND cardinality(con.conkey) = 1 AND ns.nspname = $1 "+
+			"ORDER BY cl.relname, att.attnum",
+		schema,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying foreign keys")
+	}
+	for _, row := range keyRows {
+		if table, ok := byName[row.Table]; ok {
+			table.foreignKeys = append(table.foreignKeys, foreignKey{
+				column:    row.Column,
+				refTable:  row.RefTable,
+				refColumn: row.RefColumn,
+			})
+		}
+	}
+	return tables, nil
+}
+
+// generate renders Go source of models of tables
+func generate(pkg string, tables []*schemaTable) ([]byte, error) {
+	models := buildModels(tables)
+	imports := map[string]bool{}
+	var body bytes.Buffer
+	for _, m := range models {
+		fmt.Fprintf(&body, "\n// %s is a model of %s table\ntype %s struct {\n", m.name, m.table.name, m.name)
+		for _, field := range m.fields {
+			fmt.Fprintf(&body, "\t%s %s `db:\"%s\"`\n", field.name, field.typ, field.tag)
+			switch {
+			case strings.Contains(field.typ, "null."):
+				imports["gopkg.in/guregu/null.v3"] = true
+			case strings.Contains(field.typ, "time."):
+				imports["time"] = true
+			}
+		}
+		body.WriteString("}\n")
+		if dmpr.DefaultNaming(m.name) != m.table.name {
+			fmt.Fprintf(&body, "\n// TableName returns the table name of %s\n", m.name)
+			fmt.Fprintf(&body, "func (%s) TableName() string {\n\treturn %q\n}\n", m.name, m.table.name)
+		}
+	}
+	return formatSource("gen", pkg, imports, body.Bytes())
+}
+
+// formatSource renders a generated Go source file of a dmpr command with
+// its imports, standard library packages first, and formats it
+func formatSource(command, pkg string, imports map[string]bool, body []byte) ([]byte, error) {
+	var src bytes.Buffer
+	fmt.Fprintf(&src, "// Code generated by dmpr %s. DO NOT EDIT.\n\n", command)
+	fmt.Fprintf(&src, "package %s\n", pkg)
+	if len(imports) > 0 {
+		var std, others []string
+		for path := range imports {
+			if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+				others = append(others, path)
+			} else {
+				std = append(std, path)
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(others)
+		src.WriteString("\nimport (\n")
+		for idx, group := range [][]string{std, others} {
+			if idx > 0 && len(std) > 0 && len(group) > 0 {
+				src.WriteString("\n")
+			}
+			for _, path := range group {
+				fmt.Fprintf(&src, "\t%q\n", path)
+			}
+		}
+		src.WriteString(")\n")
+	}
+	src.Write(body)
+	formatted, err := format.Source(src.Bytes())
+	if err != nil {
+		return nil, errors.Wrap(err, "formatting generated code")
+	}
+	return formatted, nil
+}
+
+// buildModels builds structs of tables, except pure linker tables, which
+// become "many-to-many" relations of the tables they link
+func buildModels(tables []*schemaTable) []*model {
+	models := []*model{}
+	byTable := map[string]*model{}
+	for _, table := range tables {
+		if isLinkTable(table) {
+			continue
+		}
+		m := &model{table: table, name: structName(table.name)}
+		for _, column := range table.columns {
+			typ, opt := goType(column)
+			tag := column.name
+			if opt != "" {
+				tag += "," + opt
+			}
+			m.fields = append(m.fields, structField{name: flect.Pascalize(column.name), typ: typ, tag: tag})
+		}
+		models = append(models, m)
+		byTable[table.name] = m
+	}
+	for _, table := range tables {
+		if isLinkTable(table) {
+			first, second := table.foreignKeys[0], table.foreignKeys[1]
+			for _, pair := range [][2]foreignKey{{first, second}, {second, first}} {
+				owner, other := byTable[pair[0].refTable], byTable[pair[1].refTable]
+				if owner == nil || other == nil {
+					continue
+				}
+				relindex, reverse := referenceStub(pair[0]), referenceStub(pair[1])
+				owner.addRelation(other.table.name, reverse, "[]*"+other.name, fmt.Sprintf(
+					"relation=%s,reverse=%s,through=%s", relindex, reverse, table.name,
+				))
+			}
+			continue
+		}
+		m := byTable[table.name]
+		for _, key := range table.foreignKeys {
+			ref := byTable[key.refTable]
+			stub := referenceStub(key)
+			if ref == nil || stub == "" {
+				continue
+			}
+			m.addRelation(stub, "", "*"+ref.name, "belongs")
+			ref.addRelation(table.name, stub, "[]*"+m.name, "relation="+stub)
+		}
+	}
+	return models
+}
+
+// addRelation adds a relation field to a model. If its name is already
+// taken, it's prefixed with prefix, or skipped if it's still taken.
+func (m *model) addRelation(name, prefix, typ, opts string) {
+	if m.hasField(name) && prefix != "" {
+		name = prefix + "_" + name
+	}
+	if m.hasField(name) {
+		return
+	}
+	m.fields = append(m.fields, structField{name: flect.Pascalize(name), typ: typ, tag: name + "," + opts})
+}
+
+// hasField checks whether a model has a field named name, by its tag or
+// its Go name
+func (m *model) hasField(name string) bool {
+	for _, field := range m.fields {
+		if strings.SplitN(field.tag, ",", 2)[0] == name || field.name == flect.Pascalize(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// referenceStub returns the relation stub of a foreign key referencing an
+// "id" column through a column named stub + "_id", or an empty string
+// otherwise
+func referenceStub(key foreignKey) string {
+	if key.refColumn != "id" || !strings.HasSuffix(key.column, "_id") {
+		return ""
+	}
+	return strings.TrimSuffix(key.column, "_id")
+}
+
+// isLinkTable checks whether a table is a pure linker table of a
+// "many-to-many" relation: it has two columns only, both of them
+// referencing other tables' IDs
+func isLinkTable(table *schemaTable) bool {
+	if len(table.columns) != 2 || len(table.foreignKeys) != 2 {
+		return false
+	}
+	for _, key := range table.foreignKeys {
+		if referenceStub(key) == "" {
+			return false
+		}
+	}
+	return table.foreignKeys[0].column != table.foreignKeys[1].column
+}
+
+// structName returns the model's name of a table
+func structName(table string) string {
+	return flect.Pascalize(flect.Singularize(table))
+}
+
+// goType returns the Go type of a column, and an optional "type" tag
+// option for SQL types which cannot be inferred from the Go type
+func goType(column schemaColumn) (string, string) {
+	typ, nullType, opt := "string", "null.String", ""
+	switch column.dataType {
+	case "smallint":
+		typ, nullType = "int16", "null.Int"
+	case "integer":
+		typ, nullType = "int", "null.Int"
+	case "bigint":
+		typ, nullType = "int64", "null.Int"
+	case "boolean":
+		typ, nullType = "bool", "null.Bool"
+	case "real":
+		typ, nullType = "float32", "null.Float"
+	case "double precision":
+		typ, nullType = "float64", "null.Float"
+	case "numeric":
+		// kept as text, as floats lose precision of decimals
+		opt = "type=numeric"
+	case "date", "timestamp with time zone", "timestamp without time zone":
+		typ, nullType = "time.Time", "null.Time"
+	case "bytea":
+		typ, nullType = "[]byte", "[]byte"
+	case "json", "jsonb":
+		typ, nullType, opt = "[]byte", "[]byte", "type="+column.dataType
+	case "uuid":
+		opt = "type=uuid"
+	}
+	if column.nullable {
+		return nullType, opt
+	}
+	return typ, opt
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/dmpr"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+)
+
+var testTables = []*schemaTable{
+	{
+		name: "accounts",
+		columns: []schemaColumn{
+			{name: "id", dataType: "integer"},
+			{name: "name", dataType: "text"},
+			{name: "settings", dataType: "jsonb", nullable: true},
+			{name: "balance", dataType: "numeric"},
+		},
+	},
+	{
+		name: "groups",
+		columns: []schemaColumn{
+			{name: "id", dataType: "bigint"},
+			{name: "name", dataType: "character varying"},
+		},
+	},
+	{
+		name: "people",
+		columns: []schemaColumn{
+			{name: "id", dataType: "bigint"},
+			{name: "parent_id", dataType: "bigint", nullable: true},
+			{name: "nickname", dataType: "text", nullable: true},
+		},
+		foreignKeys: []foreignKey{
+			{column: "parent_id", refTable: "people", refColumn: "id"},
+		},
+	},
+	{
+		name: "user_groups",
+		columns: []schemaColumn{
+			{name: "user_id", dataType: "bigint"},
+			{name: "group_id", dataType: "bigint"},
+		},
+		foreignKeys: []foreignKey{
+			{column: "user_id", refTable: "users", refColumn: "id"},
+			{column: "group_id", refTable: "groups", refColumn: "id"},
+		},
+	},
+	{
+		name: "users",
+		columns: []schemaColumn{
+			{name: "id", dataType: "bigint"},
+			{name: "account_id", dataType: "integer", nullable: true},
+			{name: "email", dataType: "text"},
+			{name: "created_at", dataType: "timestamp with time zone"},
+		},
+		foreignKeys: []foreignKey{
+			{column: "account_id", refTable: "accounts", refColumn: "id"},
+		},
+	},
+}
+
+const expectedSource = "// Code generated by dmpr gen. DO NOT EDIT.\n" + `
+package models
+
+import (
+	"time"
+
+	"gopkg.in/guregu/null.v3"
+)
+
+// Account is a model of accounts table
+type Account struct {
+	ID       int     ` + "`" + `db:"id"` + "`" + `
+	Name     string  ` + "`" + `db:"name"` + "`" + `
+	Settings []byte  ` + "`" + `db:"settings,type=jsonb"` + "`" + `
+	Balance  string  ` + "`" + `db:"balance,type=numeric"` + "`" + `
+	Users    []*User ` + "`" + `db:"users,relation=account"` + "`" + `
+}
+
+// Group is a model of groups table
+type Group struct {
+	ID    int64   ` + "`" + `db:"id"` + "`" + `
+	Name  string  ` + "`" + `db:"name"` + "`" + `
+	Users []*User ` + "`" + `db:"users,relation=group,reverse=user,through=user_groups"` + "`" + `
+}
+
+// Person is a model of people table
+type Person struct {
+	ID       int64       ` + "`" + `db:"id"` + "`" + `
+	ParentID null.Int    ` + "`" + `db:"parent_id"` + "`" + `
+	Nickname null.String ` + "`" + `db:"nickname"` + "`" + `
+	Parent   *Person     ` + "`" + `db:"parent,belongs"` + "`" + `
+	People   []*Person   ` + "`" + `db:"people,relation=parent"` + "`" + `
+}
+
+// User is a model of users table
+type User struct {
+	ID        int64     ` + "`" + `db:"id"` + "`" + `
+	AccountID null.Int  ` + "`" + `db:"account_id"` + "`" + `
+	Email     string    ` + "`" + `db:"email"` + "`" + `
+	CreatedAt time.Time ` + "`" + `db:"created_at"` + "`" + `
+	Groups    []*Group  ` + "`" + `db:"groups,relation=user,reverse=group,through=user_groups"` + "`" + `
+	Account   *Account  ` + "`" + `db:"account,belongs"` + "`" + `
+}
+`
+
+func TestGenerate(t *testing.T) {
+	src, err := generate("models", testTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(src) != expectedSource {
+		t.Errorf("generated code doesn't match.\nExpected:\n%s\nReceived:\n%s", expectedSource, src)
+	}
+}
+
+func TestBuildModels_conflicts(t *testing.T) {
+	tables := []*schemaTable{
+		{name: "users", columns: []schemaColumn{{name: "id", dataType: "bigint"}}},
+		{
+			name: "posts",
+			columns: []schemaColumn{
+				{name: "id", dataType: "bigint"},
+				{name: "author_id", dataType: "bigint"},
+				{name: "editor_id", dataType: "bigint"},
+				{name: "legacy_user", dataType: "bigint"},
+			},
+			foreignKeys: []foreignKey{
+				{column: "author_id", refTable: "users", refColumn: "id"},
+				{column: "editor_id", refTable: "users", refColumn: "id"},
+				{column: "legacy_user", refTable: "users", refColumn: "id"},
+			},
+		},
+	}
+	models := buildModels(tables)
+	expected := []structField{
+		{name: "ID", typ: "int64", tag: "id"},
+		{name: "Posts", typ: "[]*Post", tag: "posts,relation=author"},
+		{name: "EditorPosts", typ: "[]*Post", tag: "editor_posts,relation=editor"},
+	}
+	if !reflect.DeepEqual(expected, models[0].fields) {
+		t.Errorf("fields don't match.\nExpected: %+v\nReceived: %+v", expected, models[0].fields)
+	}
+}
+
+// fkQuery matches the foreign key query, which joins constraint columns
+// by table OIDs instead of constraint names
+var fkQuery = regexp.QuoteMeta("FROM pg_constraint con ") + ".*" +
+	regexp.QuoteMeta("JOIN pg_attribute att ON (att.attrelid = con.conrelid AND att.attnum = con.conkey[1]) ") +
+	regexp.QuoteMeta("JOIN pg_attribute fatt ON (fatt.attrelid = con.confrelid AND fatt.attnum = con.confkey[1]) ")
+
+func TestIntrospect(t *testing.T) {
+	tests := []struct {
+		name     string
+		mocks    func(sqlmock.Sqlmock)
+		expected []*schemaTable
+		err      error
+	}{
+		{
+			name: "tables, columns, and foreign keys",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("^SELECT table_name FROM information_schema\\.tables ").
+					WithArgs("public").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("accounts").AddRow("users"))
+				mock.ExpectQuery("^SELECT table_name, column_name, data_type, is_nullable FROM information_schema\\.columns ").
+					WithArgs("public").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "data_type", "is_nullable"}).
+						AddRow("accounts", "id", "integer", "NO").
+						AddRow("users", "id", "bigint", "NO").
+						AddRow("users", "account_id", "integer", "YES").
+						AddRow("views", "id", "bigint", "YES"))
+				mock.ExpectQuery(fkQuery).
+					WithArgs("public").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "foreign_table_name", "foreign_column_name"}).
+						AddRow("users", "account_id", "accounts", "id"))
+			},
+			expected: []*schemaTable{
+				{name: "accounts", columns: []schemaColumn{{name: "id", dataType: "integer"}}},
+				{
+					name: "users",
+					columns: []schemaColumn{
+						{name: "id", dataType: "bigint"},
+						{name: "account_id", dataType: "integer", nullable: true},
+					},
+					foreignKeys: []foreignKey{{column: "account_id", refTable: "accounts", refColumn: "id"}},
+				},
+			},
+		},
+		{
+			name: "duplicate constraint names",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("^SELECT table_name FROM information_schema\\.tables ").
+					WithArgs("public").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name"}).AddRow("accounts").AddRow("posts").AddRow("users"))
+				mock.ExpectQuery("^SELECT table_name, column_name, data_type, is_nullable FROM information_schema\\.columns ").
+					WithArgs("public").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "data_type", "is_nullable"}).
+						AddRow("accounts", "id", "integer", "NO").
+						AddRow("posts", "account_id", "integer", "NO").
+						AddRow("users", "account_id", "integer", "NO"))
+				// both tables have an "account_fk" constraint
+				mock.ExpectQuery(fkQuery).
+					WithArgs("public").
+					WillReturnRows(sqlmock.NewRows([]string{"table_name", "column_name", "foreign_table_name", "foreign_column_name"}).
+						AddRow("posts", "account_id", "accounts", "id").
+						AddRow("users", "account_id", "accounts", "id"))
+			},
+			expected: []*schemaTable{
+				{name: "accounts", columns: []schemaColumn{{name: "id", dataType: "integer"}}},
+				{
+					name:        "posts",
+					columns:     []schemaColumn{{name: "account_id", dataType: "integer"}},
+					foreignKeys: []foreignKey{{column: "account_id", refTable: "accounts", refColumn: "id"}},
+				},
+				{
+					name:        "users",
+					columns:     []schemaColumn{{name: "account_id", dataType: "integer"}},
+					foreignKeys: []foreignKey{{column: "account_id", refTable: "accounts", refColumn: "id"}},
+				},
+			},
+		},
+		{
+			name: "query error",
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery("^SELECT table_name FROM information_schema\\.tables ").
+					WillReturnError(errors.New("permission denied"))
+			},
+			err: errors.New("querying tables: permission denied"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mocks(mock)
+			mapper := dmpr.New("")
+			mapper.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
+			tables, err := introspect(mapper, "public")
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if err == nil && !reflect.DeepEqual(tt.expected, tables) {
+				t.Errorf("results don't match.\nExpected: %+v\nReceived: %+v", tt.expected, tables)
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+// Command dmpr provides tools for projects using dmpr.
+//
+// Usage:
+//
+//	dmpr gen [-url URL] [-schema public] [-package models] [-o models.go]
+//	dmpr accessors [-dir .] [-types Model,...] [-o dmpr_accessors.go]
+//
+// The gen command introspects tables, columns, and foreign keys of a
+// database schema, and generates model structs with their "db" tags,
+// including "belongs to" relations from foreign keys, "has many"
+// back-references, and "many to many" relations through pure linker
+// tables. Database URL is read from DATABASE_URL environment variable by
+// default.
+//
+// The accessors command reads model structs of a Go package, and generates
+// dmpr.Accessor implementations for them: static column lists, scan targets,
+// and INSERT / UPDATE queries, which let Mapper work with the models without
+// reflection. By default, all structs with "db" tags are processed.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/julian7/dmpr"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+	var err error
+	switch os.Args[1] {
+	case "gen":
+		err = runGen(os.Args[2:])
+	case "accessors":
+		err = runAccessors(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "dmpr %s: %v\n", os.Args[1], err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: dmpr gen|accessors [flags]")
+	os.Exit(2)
+}
+
+func runGen(args []string) error {
+	flags := flag.NewFlagSet("gen", flag.ExitOnError)
+	url := flags.String("url", os.Getenv("DATABASE_URL"), "database URL")
+	schema := flags.String("schema", "public", "database schema to introspect")
+	pkg := flags.String("package", "models", "package name of generated code")
+	out := flags.String("o", "", "output file (default: standard output)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *url == "" {
+		return fmt.Errorf("database URL is not set")
+	}
+	mapper := dmpr.New(*url)
+	tables, err := introspect(mapper, *schema)
+	if err != nil {
+		return err
+	}
+	src, err := generate(*pkg, tables)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(*out, src, 0644)
+}
+
+func runAccessors(args []string) error {
+	flags := flag.NewFlagSet("accessors", flag.ExitOnError)
+	dir := flags.String("dir", ".", "directory of the models' package")
+	types := flags.String("types", "", "comma separated list of model types (default: all structs with db tags)")
+	out := flags.String("o", "dmpr_accessors.go", "output file, relative to -dir")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	var typeList []string
+	if *types != "" {
+		typeList = strings.Split(*types, ",")
+	}
+	output := *out
+	if !filepath.IsAbs(output) {
+		output = filepath.Join(*dir, output)
+	}
+	pkg, models, err := parseModels(*dir, output, typeList)
+	if err != nil {
+		return err
+	}
+	src, err := generateAccessors(pkg, models)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(output, src, 0644)
+}
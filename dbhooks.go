@@ -1,6 +1,7 @@
 package dmpr
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/jmoiron/sqlx"
@@ -12,7 +13,15 @@ func (m *Mapper) Exec(query string, args ...interface{}) (sql.Result, error) {
 		return nil, err
 	}
 	m.logger.Debugf("DB EXEC: %s with %+v", query, args)
-	return m.Conn.Exec(query, args...)
+	stmt, release, err := m.prepared(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt != nil {
+		defer release()
+		return stmt.Exec(args...)
+	}
+	return m.ext().Exec(query, args...)
 }
 
 // NamedExec runs sqlx.NamedExec nicely. It opens database if needed, and logs the query.
@@ -21,7 +30,14 @@ func (m *Mapper) NamedExec(query string, arg interface{}) (sql.Result, error) {
 		return nil, err
 	}
 	m.logger.Debugf("DB NAMED EXEC: %s with %+v", query, arg)
-	return m.Conn.NamedExec(query, arg)
+	if m.statements != nil {
+		bound, args, err := m.Conn.BindNamed(query, arg)
+		if err != nil {
+			return nil, err
+		}
+		return m.Exec(bound, args...)
+	}
+	return sqlx.NamedExec(m.ext(), query, arg)
 }
 
 // NamedQuery runs sqlx.NamedQuery nicely. It opens database if needed, and logs the query.
@@ -30,7 +46,14 @@ func (m *Mapper) NamedQuery(query string, arg interface{}) (*sqlx.Rows, error) {
 		return nil, err
 	}
 	m.logger.Debugf("DB NAMED QUERY: %s with %+v", query, arg)
-	return m.Conn.NamedQuery(query, arg)
+	if m.statements != nil {
+		bound, args, err := m.Conn.BindNamed(query, arg)
+		if err != nil {
+			return nil, err
+		}
+		return m.Queryx(bound, args...)
+	}
+	return sqlx.NamedQuery(m.ext(), query, arg)
 }
 
 // Get runs sqlx.Get nicely. It opens database if needed, and logs the query.
@@ -39,7 +62,15 @@ func (m *Mapper) Get(dest interface{}, query string, args ...interface{}) error
 		return err
 	}
 	m.logger.Debugf("DB GET: %s with %+v", query, args)
-	return m.Conn.Get(dest, query, args...)
+	stmt, release, err := m.prepared(query)
+	if err != nil {
+		return err
+	}
+	if stmt != nil {
+		defer release()
+		return stmt.Get(dest, args...)
+	}
+	return sqlx.Get(m.ext(), dest, query, args...)
 }
 
 // Select runs sqlx.Select nicely. It opens database if needed, and logs the query.
@@ -48,7 +79,35 @@ func (m *Mapper) Select(dest interface{}, query string, args ...interface{}) err
 		return err
 	}
 	m.logger.Debugf("DB SELECT: %s with %+v", query, args)
-	return m.Conn.Select(dest, query, args...)
+	stmt, release, err := m.prepared(query)
+	if err != nil {
+		return err
+	}
+	if stmt != nil {
+		defer release()
+		return stmt.Select(dest, args...)
+	}
+	return sqlx.Select(m.ext(), dest, query, args...)
+}
+
+// selectContext is Select running the query with a context
+func (m *Mapper) selectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	if err := m.tryOpen(); err != nil {
+		return err
+	}
+	m.logger.Debugf("DB SELECT: %s with %+v", query, args)
+	stmt, release, err := m.prepared(query)
+	if err != nil {
+		return err
+	}
+	if stmt != nil {
+		defer release()
+		return stmt.SelectContext(ctx, dest, args...)
+	}
+	if m.tx != nil {
+		return sqlx.SelectContext(ctx, m.tx, dest, query, args...)
+	}
+	return sqlx.SelectContext(ctx, m.Conn, dest, query, args...)
 }
 
 // Queryx runs sqlx.Queryx nicely. It opens database if needed, and logs the query.
@@ -57,5 +116,13 @@ func (m *Mapper) Queryx(query string, args ...interface{}) (*sqlx.Rows, error) {
 		return nil, err
 	}
 	m.logger.Debugf("DB QUERYX: %s with %+v", query, args)
-	return m.Conn.Queryx(query, args...)
+	stmt, release, err := m.prepared(query)
+	if err != nil {
+		return nil, err
+	}
+	if stmt != nil {
+		defer release()
+		return stmt.Queryx(args...)
+	}
+	return m.ext().Queryx(query, args...)
 }
@@ -0,0 +1,315 @@
+package dmpr
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/guregu/null.v3"
+)
+
+// nullableTypes are SQL types of nullable Go types
+var nullableTypes = map[reflect.Type]string{
+	reflect.TypeOf(sql.NullBool{}):    "boolean",
+	reflect.TypeOf(sql.NullFloat64{}): "double precision",
+	reflect.TypeOf(sql.NullInt32{}):   "integer",
+	reflect.TypeOf(sql.NullInt64{}):   "bigint",
+	reflect.TypeOf(sql.NullString{}):  "text",
+	reflect.TypeOf(sql.NullTime{}):    "timestamptz",
+	reflect.TypeOf(null.Bool{}):       "boolean",
+	reflect.TypeOf(null.Float{}):      "double precision",
+	reflect.TypeOf(null.Int{}):        "bigint",
+	reflect.TypeOf(null.String{}):     "text",
+	reflect.TypeOf(null.Time{}):       "timestamptz",
+}
+
+// columnType returns the SQL type of a Go type, and whether it's nullable
+func columnType(t reflect.Type) (string, bool, error) {
+	if sqlType, ok := nullableTypes[t]; ok {
+		return sqlType, true, nil
+	}
+	if t.Kind() == reflect.Ptr {
+		sqlType, _, err := columnType(t.Elem())
+		return sqlType, true, err
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return "timestamptz", false, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean", false, nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "smallint", false, nil
+	case reflect.Int32, reflect.Uint16:
+		return "integer", false, nil
+	case reflect.Int, reflect.Int64, reflect.Uint32, reflect.Uint, reflect.Uint64:
+		return "bigint", false, nil
+	case reflect.Float32:
+		return "real", false, nil
+	case reflect.Float64:
+		return "double precision", false, nil
+	case reflect.String:
+		return "text", false, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "bytea", false, nil
+		}
+	}
+	return "", false, errors.Errorf("cannot infer SQL type of %s", t)
+}
+
+// CreateTableSQL returns DDL statements creating the model's table, and the
+// linker tables of its "many-to-many" relations, if they don't exist.
+// Column types are inferred from fields' types (see OptType to override
+// them): pointers, sql.Null* and null.v3 types are nullable, other columns
+// are NOT NULL. An integer "id" column becomes a serial primary key, and
+// "belongs to" relations' reference columns get foreign keys. Read-only
+// fields and relations are skipped.
+func (m *Mapper) CreateTableSQL(model interface{}) ([]string, error) {
+	t, _ := Reflect(model)
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, ErrInvalidType
+	}
+	table, err := m.tableNameByType(t)
+	if err != nil {
+		return nil, err
+	}
+	fl := m.FieldList(t)
+	if fl == nil {
+		return nil, errors.New("cannot get field list")
+	}
+	references, err := m.foreignKeys(fl)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := fl.FieldsFor()
+	if err != nil {
+		return nil, err
+	}
+	columns := make([]string, 0, len(fields))
+	for _, field := range fields {
+		index, _ := fl.columnIndex(field.key)
+		sqlType, nullable, err := columnType(t.FieldByIndex(index).Type)
+		if custom, ok := field.opts[OptType]; ok && custom != "" {
+			sqlType, err = custom, nil
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "column %q of %s", field.key, t)
+		}
+		column := quoteIdentifier(field.key) + " " + sqlType
+		switch {
+		case field.key == "id":
+			column = quoteIdentifier(field.key) + " " + serialType(sqlType) + " PRIMARY KEY"
+		case !nullable:
+			column += " NOT NULL"
+		}
+		if reference, ok := references[field.key]; ok {
+			column += " REFERENCES " + quoteIdentifier(reference) + " (id)"
+		}
+		columns = append(columns, column)
+	}
+	statements := []string{fmt.Sprintf(
+		"CREATE TABLE IF NOT EXISTS %s (%s)",
+		quoteIdentifier(table),
+		strings.Join(columns, ", "),
+	)}
+	links, err := m.linkTablesSQL(fl, table)
+	if err != nil {
+		return nil, err
+	}
+	return append(statements, links...), nil
+}
+
+// serialType returns the auto-incrementing variant of an integer type
+func serialType(sqlType string) string {
+	switch sqlType {
+	case "smallint":
+		return "smallserial"
+	case "integer":
+		return "serial"
+	case "bigint":
+		return "bigserial"
+	}
+	return sqlType
+}
+
+// foreignKeys returns tables referenced by "belongs to" relations, by their
+// reference columns. Polymorphic relations don't have foreign keys.
+func (m *Mapper) foreignKeys(fl *FieldList) (map[string]string, error) {
+	references := map[string]string{}
+	for _, field := range fl.Fields {
+		if _, ok := field.Options[OptBelongs]; !ok || len(field.Index) > 1 {
+			continue
+		}
+		if _, ok := field.Options[OptPolymorphic]; ok {
+			continue
+		}
+		if _, ok := fl.columnIndex(field.Path + "_id"); !ok {
+			continue
+		}
+		table, err := m.tableNameByType(deref(field.Type))
+		if err != nil {
+			return nil, err
+		}
+		references[field.Path+"_id"] = table
+	}
+	return references, nil
+}
+
+// linkTablesSQL returns DDL statements creating linker tables of the
+// model's "many-to-many" relations
+func (m *Mapper) linkTablesSQL(fl *FieldList, table string) ([]string, error) {
+	var statements []string
+	for _, field := range fl.Fields {
+		relindex, hasRelIndex := field.Options[OptRelation]
+		revindex, hasRevIndex := field.Options[OptReverse]
+		through, hasThrough := field.Options[OptThrough]
+		if !hasRelIndex || !hasRevIndex || !hasThrough || len(field.Index) > 1 {
+			continue
+		}
+		t := deref(field.Type)
+		if t.Kind() == reflect.Slice {
+			t = deref(t.Elem())
+		}
+		other, err := m.tableNameByType(t)
+		if err != nil {
+			return nil, err
+		}
+		relType, err := idColumnType(fl)
+		if err != nil {
+			return nil, err
+		}
+		revType, err := idColumnType(m.FieldList(t))
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s ("+
+				"%s %s NOT NULL REFERENCES %s (id) ON DELETE CASCADE, "+
+				"%s %s NOT NULL REFERENCES %s (id) ON DELETE CASCADE, "+
+				"PRIMARY KEY (%s, %s))",
+			quoteIdentifier(through),
+			quoteIdentifier(relindex+"_id"),
+			relType,
+			quoteIdentifier(table),
+			quoteIdentifier(revindex+"_id"),
+			revType,
+			quoteIdentifier(other),
+			quoteIdentifier(relindex+"_id"),
+			quoteIdentifier(revindex+"_id"),
+		))
+	}
+	return statements, nil
+}
+
+// idColumnType returns the SQL type of a model's "id" column, for columns
+// referencing it
+func idColumnType(fl *FieldList) (string, error) {
+	if fl == nil {
+		return "", errors.New("cannot get field list")
+	}
+	index, ok := fl.columnIndex("id")
+	if !ok {
+		return "", errors.Errorf("no ID field found in %s", fl.Type)
+	}
+	for _, field := range fl.Fields {
+		if custom := field.Options[OptType]; custom != "" && reflect.DeepEqual(field.Index, index) {
+			return custom, nil
+		}
+	}
+	sqlType, _, err := columnType(fl.Type.FieldByIndex(index).Type)
+	if err != nil {
+		return "", errors.Wrapf(err, "column \"id\" of %s", fl.Type)
+	}
+	return sqlType, nil
+}
+
+// AutoMigrate creates missing tables of models (and their linker tables)
+// with CreateTableSQL, in a transaction. Models are created in the order
+// of their "belongs to" relations, so referenced tables are created
+// first. Existing tables are not altered.
+func (m *Mapper) AutoMigrate(models ...interface{}) error {
+	types := make([]reflect.Type, 0, len(models))
+	for _, model := range models {
+		t, _ := Reflect(model)
+		if t == nil || t.Kind() != reflect.Struct {
+			return ErrInvalidType
+		}
+		types = append(types, t)
+	}
+	ordered, err := m.orderByReferences(types)
+	if err != nil {
+		return err
+	}
+	var tables, links []string
+	seen := map[string]bool{}
+	for _, t := range ordered {
+		statements, err := m.CreateTableSQL(reflect.New(t).Interface())
+		if err != nil {
+			return err
+		}
+		tables = append(tables, statements[0])
+		for _, link := range statements[1:] {
+			if !seen[link] {
+				seen[link] = true
+				links = append(links, link)
+			}
+		}
+	}
+	return m.Transaction(func(tx *Mapper) error {
+		for _, statement := range append(tables, links...) {
+			if _, err := tx.Exec(statement); err != nil {
+				return errors.Wrap(err, "AutoMigrate")
+			}
+		}
+		return nil
+	})
+}
+
+// orderByReferences sorts types topologically by their "belongs to"
+// relations, returning referenced types first
+func (m *Mapper) orderByReferences(types []reflect.Type) ([]reflect.Type, error) {
+	known := map[reflect.Type]bool{}
+	for _, t := range types {
+		known[t] = true
+	}
+	const visiting, visited = 1, 2
+	state := map[reflect.Type]int{}
+	ordered := make([]reflect.Type, 0, len(types))
+	var visit func(t reflect.Type) error
+	visit = func(t reflect.Type) error {
+		switch state[t] {
+		case visiting:
+			return errors.Errorf("circular belongs to relations of %s", t)
+		case visited:
+			return nil
+		}
+		state[t] = visiting
+		fl := m.FieldList(t)
+		if fl == nil {
+			return errors.New("cannot get field list")
+		}
+		for _, field := range fl.Fields {
+			if _, ok := field.Options[OptBelongs]; !ok || len(field.Index) > 1 {
+				continue
+			}
+			if other := deref(field.Type); other != t && known[other] {
+				if err := visit(other); err != nil {
+					return err
+				}
+			}
+		}
+		state[t] = visited
+		ordered = append(ordered, t)
+		return nil
+	}
+	for _, t := range types {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
@@ -0,0 +1,189 @@
+package dmpr
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+type ExampleSchemaAccount struct {
+	ID       int                  `db:"id"`
+	Name     string               `db:"name"`
+	Settings []byte               `db:"settings,type=jsonb"`
+	Users    []*ExampleSchemaUser `db:"users,relation=account"`
+}
+
+type ExampleSchemaUser struct {
+	ID        int32                 `db:"id"`
+	AccountID *int64                `db:"account_id"`
+	Account   *ExampleSchemaAccount `db:"account,belongs"`
+	Score     float64               `db:"score"`
+	Active    bool                  `db:"active"`
+	Born      time.Time             `db:"born"`
+	Count     int                   `db:"count,readonly"`
+	Roles     []ExampleSchemaRole   `db:"roles,relation=user,reverse=role,through=user_roles"`
+}
+
+type ExampleSchemaRole struct {
+	ID   int            `db:"id"`
+	Name sql.NullString `db:"name"`
+}
+
+type ExampleSchemaUnknown struct {
+	ID   int          `db:"id"`
+	Tags map[int]bool `db:"tags"`
+}
+
+type ExampleSchemaCycle struct {
+	ID      int                     `db:"id"`
+	OtherID int                     `db:"other_id"`
+	Other   *ExampleSchemaCycleBack `db:"other,belongs"`
+}
+
+type ExampleSchemaCycleBack struct {
+	ID      int                 `db:"id"`
+	CycleID int                 `db:"cycle_id"`
+	Cycle   *ExampleSchemaCycle `db:"cycle,belongs"`
+}
+
+const (
+	ddlAccounts = `CREATE TABLE IF NOT EXISTS example_schema_accounts (id bigserial PRIMARY KEY, name text NOT NULL, settings jsonb NOT NULL)`
+	ddlUsers    = `CREATE TABLE IF NOT EXISTS example_schema_users (id serial PRIMARY KEY, ` +
+		`account_id bigint REFERENCES example_schema_accounts (id), score double precision NOT NULL, ` +
+		`active boolean NOT NULL, born timestamptz NOT NULL)`
+	ddlUserRoles = `CREATE TABLE IF NOT EXISTS user_roles (` +
+		`user_id integer NOT NULL REFERENCES example_schema_users (id) ON DELETE CASCADE, ` +
+		`role_id bigint NOT NULL REFERENCES example_schema_roles (id) ON DELETE CASCADE, ` +
+		`PRIMARY KEY (user_id, role_id))`
+	ddlRoles = `CREATE TABLE IF NOT EXISTS example_schema_roles (id bigserial PRIMARY KEY, name text)`
+)
+
+func TestMapper_CreateTableSQL(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    interface{}
+		expected []string
+		err      error
+	}{
+		{
+			name:  "nullable types",
+			model: &ExampleModel{},
+			expected: []string{
+				`CREATE TABLE IF NOT EXISTS example_models (id bigserial PRIMARY KEY, name text NOT NULL, extra text, created_at timestamptz)`,
+			},
+		},
+		{
+			name:     "custom type",
+			model:    &ExampleSchemaAccount{},
+			expected: []string{ddlAccounts},
+		},
+		{
+			name:     "foreign keys and link tables",
+			model:    &ExampleSchemaUser{},
+			expected: []string{ddlUsers, ddlUserRoles},
+		},
+		{
+			name:  "unknown type",
+			model: &ExampleSchemaUnknown{},
+			err:   errors.New(`column "tags" of dmpr.ExampleSchemaUnknown: cannot infer SQL type of map[int]bool`),
+		},
+		{
+			name:  "not a struct",
+			model: &[]int{},
+			err:   ErrInvalidType,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, _, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			m := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			statements, err := m.CreateTableSQL(tt.model)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err != nil {
+				return
+			}
+			if len(statements) != len(tt.expected) {
+				t.Fatalf("expected %d statements, received %d: %v", len(tt.expected), len(statements), statements)
+			}
+			for idx := range statements {
+				if statements[idx] != tt.expected[idx] {
+					t.Errorf("statement %d doesn't match.\nExpected: %s\nReceived: %s", idx, tt.expected[idx], statements[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestMapper_AutoMigrate(t *testing.T) {
+	tests := []struct {
+		name   string
+		models []interface{}
+		mocks  func(sqlmock.Sqlmock)
+		err    error
+	}{
+		{
+			name:   "referenced tables first",
+			models: []interface{}{&ExampleSchemaUser{}, &ExampleSchemaRole{}, ExampleSchemaAccount{}},
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				for _, statement := range []string{ddlAccounts, ddlUsers, ddlRoles, ddlUserRoles} {
+					mock.ExpectExec("^" + regexp.QuoteMeta(statement) + "$").WillReturnResult(sqlmock.NewResult(0, 0))
+				}
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:   "failing statement",
+			models: []interface{}{&ExampleSchemaRole{}},
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("^" + regexp.QuoteMeta(ddlRoles) + "$").WillReturnError(errors.New("permission denied"))
+				mock.ExpectRollback()
+			},
+			err: errors.New("AutoMigrate: permission denied"),
+		},
+		{
+			name:   "circular references",
+			models: []interface{}{&ExampleSchemaCycle{}, &ExampleSchemaCycleBack{}},
+			mocks:  func(mock sqlmock.Sqlmock) {},
+			err:    errors.New("circular belongs to relations of dmpr.ExampleSchemaCycle"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mocks(mock)
+			m := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			err = m.AutoMigrate(tt.models...)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
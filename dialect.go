@@ -0,0 +1,68 @@
+package dmpr
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Dialect describes database specific SQL rendering rules.
+type Dialect interface {
+	// QuoteIdentifier returns a single (not qualified) identifier in a form
+	// it can be safely embedded into SQL queries.
+	QuoteIdentifier(name string) string
+}
+
+// Postgres is the PostgreSQL dialect. It quotes identifiers with double
+// quotes, but only when it is necessary: when the identifier is a reserved
+// word, or it contains characters other than lowercase letters, digits,
+// and underscores.
+type Postgres struct{}
+
+// DefaultDialect is the dialect all queries are rendered with.
+var DefaultDialect Dialect = Postgres{}
+
+var plainIdentifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+var postgresReserved = map[string]bool{}
+
+func init() {
+	for _, word := range strings.Fields(`all analyse analyze and any array as asc
+		asymmetric authorization binary both case cast check collate collation
+		column concurrently constraint create cross current_catalog current_date
+		current_role current_schema current_time current_timestamp current_user
+		default deferrable desc distinct do else end except false fetch for
+		foreign freeze from full grant group having ilike in initially inner
+		intersect into is isnull join lateral leading left like limit localtime
+		localtimestamp natural not notnull null offset on only or order outer
+		overlaps placing primary references returning right select session_user
+		similar some symmetric system_user table tablesample then to trailing
+		true union unique user using variadic verbose when where window with`) {
+		postgresReserved[word] = true
+	}
+}
+
+// QuoteIdentifier quotes an identifier if it is necessary
+func (Postgres) QuoteIdentifier(name string) string {
+	if plainIdentifier.MatchString(name) && !postgresReserved[name] {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// quoteIdentifier quotes a possibly qualified identifier (like
+// `schema.table` or `t1.column`) part by part with the default dialect.
+// A `*` part is kept as is.
+func quoteIdentifier(name string) string {
+	parts := strings.Split(name, ".")
+	for idx, part := range parts {
+		if part != "*" {
+			parts[idx] = DefaultDialect.QuoteIdentifier(part)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// quoteLiteral returns s as an SQL string literal
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,26 @@
+package dmpr
+
+import "testing"
+
+func Test_quoteIdentifier(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "name", want: "name"},
+		{name: "reserved", in: "user", want: `"user"`},
+		{name: "qualified", in: "t1.order", want: `t1."order"`},
+		{name: "schema qualified", in: "legacy.tbl_users", want: "legacy.tbl_users"},
+		{name: "star", in: "t2.*", want: "t2.*"},
+		{name: "mixed case", in: "UserName", want: `"UserName"`},
+		{name: "injection", in: `x"; DROP TABLE users; --`, want: `"x""; DROP TABLE users; --"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteIdentifier(tt.in); got != tt.want {
+				t.Errorf("quoteIdentifier() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
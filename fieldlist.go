@@ -32,8 +32,53 @@ const (
 	// OptThrough is a struct tag option marking a "many-to-many" relation,
 	// containing the linker table's name. See OptReverse for an example.
 	// OptThrough is taken into consideration only if OptRelation and OptReverse
-	// are also provided.
+	// are also provided, or, for "has many through" relations, if OptVia is
+	// provided (see there).
 	OptThrough = "through"
+	// OptPolymorphic is a struct tag option marking a polymorphic relation,
+	// where the other end can reference models of different types. Its value
+	// is the reference's stub: the referencing table stores the referenced
+	// model's ID in stub + "_id", and its type in stub + "_type" columns.
+	// Type is the referenced model's table name by default (see
+	// OptPolyType).
+	//
+	// On the referenced side, it marks a "has one" or "has many" relation,
+	// replacing OptRelation. Example tag: `db:"comments,polymorphic=commentable"`.
+	// On the referencing side, it is used together with OptBelongs, with a
+	// field for each possible type. Example tag:
+	// `db:"post,belongs,polymorphic=commentable"`.
+	OptPolymorphic = "polymorphic"
+	// OptPolyType is a struct tag option, which overrides the type name
+	// stored in a polymorphic relation's type column for the model. It has
+	// to be set on both ends of the relation.
+	OptPolyType = "polytype"
+	// OptVia is a struct tag option marking a "has many through" relation,
+	// which goes through an intermediate model, instead of an anonymous
+	// linker table. In this case, OptThrough contains the name of a "has
+	// many" relation to the intermediate model, and OptVia contains the name
+	// of the intermediate model's "belongs to" relation to the joined model.
+	//
+	// Example tag: `db:"patients,through=appointments,via=patient"`: this
+	// model has an `appointments` relation (like `db:"appointments,relation=doctor"`),
+	// and the intermediate model has a `patient` relation (like
+	// `db:"patient,belongs"`).
+	OptVia = "via"
+	// OptLinks is a struct tag option for "has many through" relations (see
+	// OptVia), which makes joining the relation fill the intermediate
+	// relation too, with one intermediate model for each joined model.
+	OptLinks = "links"
+	// OptReadOnly is a struct tag option marking a field, which is not a
+	// column of the model's table, but it can be filled by queries, like
+	// relation aggregates (see SelectQuery.WithCount). Read-only fields are
+	// not selected by default, and they are not saved.
+	//
+	// Example tag: `db:"to_do_items_count,readonly"`.
+	OptReadOnly = "readonly"
+	// OptType is a struct tag option, which sets a column's SQL type for
+	// CreateTableSQL, overriding the type inferred from the field's Go type.
+	// Example tag: `db:"settings,type=jsonb"`. Types with commas (like
+	// "numeric(10,2)") cannot be set this way.
+	OptType = "type"
 )
 
 // FieldList stores fields of a reflectx.StructMap's Index (from sqlx), with the structure's type
@@ -41,6 +86,8 @@ type FieldList struct {
 	Fields []FieldListItem
 	Type   reflect.Type
 	Joins  map[string]*FieldList
+	mapper *Mapper
+	meta   *modelMetadata
 }
 
 // FieldListItem is a line item of a model's field list
@@ -54,6 +101,17 @@ type FieldListItem struct {
 	Traversed bool
 }
 
+// UnknownColumnError is returned when a query references a column, which is
+// not found in the model.
+type UnknownColumnError struct {
+	Model  reflect.Type
+	Column string
+}
+
+func (e *UnknownColumnError) Error() string {
+	return fmt.Sprintf("unknown column %q in model %s", e.Column, e.Model)
+}
+
 // QueryField is a conversion struct for building INSERT or UPDATE queries
 type QueryField struct {
 	key  string
@@ -67,105 +125,209 @@ type Traversal struct {
 	Name     string
 	Index    []int
 	Relation reflect.StructField
+	// relDepth is the length of Index prefix pointing to the related model's
+	// field, if the column belongs to a related model; 0 otherwise.
+	relDepth int
+	typ      reflect.Type
 }
 
 // Traversals is an array of Traversal
 type Traversals []*Traversal
 
-// FieldList returns a map of types in the form of a StructMap, from the original model's type
+// FieldList returns a map of types in the form of a StructMap, from the
+// original model's type. The field list is computed once per type, and
+// it's copied for each call, as query builders mark its fields traversed.
 func (m *Mapper) FieldList(t reflect.Type) *FieldList {
 	if err := m.tryOpen(); err != nil {
 		m.logger.Warnf("cannot get type map of %+v: %v", t, err)
 		return nil
 	}
 	t = deref(t)
-	fieldList := &FieldList{Type: t}
-	fl := m.Conn.Mapper.TypeMap(t).Index
-	related := []string{}
-	for _, fi := range fl {
-		if _, ok := fi.Options[OptBelongs]; ok {
-			related = append(related, fi.Path)
-		}
+	meta := m.modelMetadata(t)
+	return &FieldList{
+		Fields: append(make([]FieldListItem, 0, len(meta.fields)), meta.fields...),
+		Type:   t,
+		mapper: m,
+		meta:   meta,
 	}
-	fieldList.Fields = make([]FieldListItem, 0, len(fl))
-	for _, fi := range fl {
-		if fi.Parent.Field.Type != nil {
-			found := false
-			for _, rel := range related {
-				if fi.Parent.Path == rel {
-					found = true
-					fi.Options[OptRelatedTo] = rel
-					break
-				}
-			}
-			if !found {
-				fi.Options[OptUnrelated] = ""
-			}
-		}
-		fieldStruct := t.FieldByIndex(fi.Index)
-		fieldList.Fields = append(fieldList.Fields, FieldListItem{
-			Field:     fieldStruct,
-			Index:     fi.Index,
-			Name:      fi.Name,
-			Options:   fi.Options,
-			Path:      strings.ReplaceAll(fi.Path, ".", "_"),
-			Traversed: false,
-			Type:      fieldStruct.Type,
-		})
-	}
-	return fieldList
 }
 
 // FieldsFor converts FieldListItems to query fields SQL query builders can use. It doesn't include related fields.
 func (fl *FieldList) FieldsFor() ([]QueryField, error) {
-	queryFields := make([]QueryField, 0, len(fl.Fields))
-FieldsForLoop:
-	for _, fi := range fl.Fields {
-		for _, item := range []string{OptRelatedTo, OptUnrelated} {
-			if _, ok := fi.Options[item]; ok {
-				continue FieldsForLoop
-			}
-		}
-		field := fi.QField()
-		if field != nil {
-			queryFields = append(queryFields, *field)
+	if fl.meta != nil {
+		fields := fl.meta.queryFields
+		return fields[:len(fields):len(fields)], nil
+	}
+	return ownFields(fl.Fields), nil
+}
+
+// selected returns the model's own columns qualified with the "t1" alias,
+// for SELECT queries
+func (fl *FieldList) selected() []string {
+	if fl.meta != nil {
+		return fl.meta.selected
+	}
+	fields, _ := fl.FieldsFor()
+	selected := make([]string, 0, len(fields))
+	for _, item := range fields {
+		selected = append(selected, "t1."+quoteIdentifier(item.key))
+	}
+	return selected
+}
+
+// ValidateColumn checks if column is a column of the model's table,
+// returning *UnknownColumnError if it isn't.
+func (fl *FieldList) ValidateColumn(column string) error {
+	fields, err := fl.FieldsFor()
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.key == column {
+			return nil
 		}
 	}
-	return queryFields, nil
+	return &UnknownColumnError{Model: fl.Type, Column: column}
 }
 
 // RelatedFieldsFor converts FieldListItems to JOINs and SELECTs SQL query builders can use directly
 func (fl *FieldList) RelatedFieldsFor(relation, tableref string, cb func(reflect.Type) *FieldList) (joins []string, selects []string, err error) {
+	clauses, selects, err := fl.relatedJoins(relation, tableref, cb)
+	return joinStrings(clauses), selects, err
+}
+
+// relatedJoins builds JOIN clauses and SELECTs of a relation
+func (fl *FieldList) relatedJoins(relation, tableref string, cb func(reflect.Type) *FieldList) ([]joinClause, []string, error) {
 	for _, field := range fl.Fields {
 		if field.Path == relation {
-			if _, ok := field.Options[OptRelation]; ok {
-				return fl.HasNFieldsFor(relation, tableref, field, cb)
+			if isHasN(field) {
+				return fl.hasNJoins(relation, tableref, field, cb)
 			}
-			tablename, err := tableNameByType(field.Type)
+			tablename, err := fl.tableNameByType(field.Type)
 			if err != nil {
 				return nil, nil, err
 			}
-			return fl.BelongsToFieldsFor(relation, tableref, tablename)
+			if poly, ok := field.Options[OptPolymorphic]; ok {
+				polytype, err := fl.polymorphicType(deref(field.Type), field)
+				if err != nil {
+					return nil, nil, err
+				}
+				return fl.belongsToJoins(relation, tableref, tablename, poly+"_id", fmt.Sprintf(
+					" AND t1.%s=%s",
+					quoteIdentifier(poly+"_type"),
+					quoteLiteral(polytype),
+				))
+			}
+			return fl.belongsToJoins(relation, tableref, tablename, relation+"_id", "")
 		}
 	}
 	return nil, nil, errors.Errorf("Relation %q not found", relation)
 }
 
+// joinClause is a JOIN clause without its keyword: a table with its alias,
+// and its join condition
+type joinClause struct {
+	table string
+	on    string
+}
+
+func (c joinClause) String() string {
+	return c.table + " ON (" + c.on + ")"
+}
+
+// joinStrings converts JOIN clauses to strings
+func joinStrings(clauses []joinClause) []string {
+	if clauses == nil {
+		return nil
+	}
+	joins := make([]string, 0, len(clauses))
+	for _, clause := range clauses {
+		joins = append(joins, clause.String())
+	}
+	return joins
+}
+
+// isHasN checks whether a field is a "has one," "has many," or "many to
+// many" relation.
+func isHasN(field FieldListItem) bool {
+	for _, opt := range []string{OptRelation, OptVia} {
+		if _, ok := field.Options[opt]; ok {
+			return true
+		}
+	}
+	_, isPoly := field.Options[OptPolymorphic]
+	_, isBelongs := field.Options[OptBelongs]
+	return isPoly && !isBelongs
+}
+
+// isRelation checks whether a field is any kind of relation
+func isRelation(field FieldListItem) bool {
+	for _, opt := range []string{OptBelongs, OptRelation, OptPolymorphic, OptVia} {
+		if _, ok := field.Options[opt]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// polymorphicType returns the type name stored in polymorphic relations for
+// a model type. Field is the relation field referencing the model; its
+// OptPolyType option overrides the default, which is the model's table name.
+func (fl *FieldList) polymorphicType(t reflect.Type, field FieldListItem) (string, error) {
+	if polytype, ok := field.Options[OptPolyType]; ok && polytype != "" {
+		return polytype, nil
+	}
+	return fl.tableNameByType(t)
+}
+
+// relationField returns the field of a relation ("belongs to," "has one,"
+// "has many," or "many to many") by its name.
+func (fl *FieldList) relationField(relation string) (FieldListItem, error) {
+	if fl.meta != nil {
+		if idx, ok := fl.meta.relations[relation]; ok {
+			return fl.Fields[idx], nil
+		}
+		return FieldListItem{}, errors.Errorf("Relation %q not found", relation)
+	}
+	for _, field := range fl.Fields {
+		if field.Path != relation {
+			continue
+		}
+		if isRelation(field) {
+			return field, nil
+		}
+	}
+	return FieldListItem{}, errors.Errorf("Relation %q not found", relation)
+}
+
 // BelongsToFieldsFor converts FieldListItems to JOIN and SELECTs query substrings SQL query buildders can use directly
 func (fl *FieldList) BelongsToFieldsFor(relation, tableref, tablename string) ([]string, []string, error) {
-	joined := []string{fmt.Sprintf("%s %s ON (t1.%s_id=%s.id)", tablename, tableref, relation, tableref)}
+	joined, selected, err := fl.belongsToJoins(relation, tableref, tablename, relation+"_id", "")
+	return joinStrings(joined), selected, err
+}
+
+// belongsToJoins builds JOIN clauses and SELECTs for a "belongs to"
+// relation, referenced by the reference column, with an extra condition
+// for the JOIN.
+func (fl *FieldList) belongsToJoins(relation, tableref, tablename, reference, extra string) ([]joinClause, []string, error) {
+	joined := []joinClause{{
+		table: quoteIdentifier(tablename) + " " + tableref,
+		on:    fmt.Sprintf("t1.%s=%s.id%s", quoteIdentifier(reference), tableref, extra),
+	}}
 	selected := []string{}
 	rel := len(relation) + 1
-FieldScan:
 	for _, fi := range fl.Fields {
-		for _, option := range []string{OptUnrelated, OptRelation, OptBelongs} {
-			if _, ok := fi.Options[option]; ok {
-				continue FieldScan
-			}
+		if _, ok := fi.Options[OptUnrelated]; ok || isRelation(fi) {
+			continue
 		}
 		if subfield, ok := fi.Options[OptRelatedTo]; ok && relation == subfield {
 			name := fi.Path[rel:]
-			selected = append(selected, fmt.Sprintf("%s.%s AS %s_%s", tableref, name, relation, name))
+			selected = append(selected, fmt.Sprintf(
+				"%s.%s AS %s",
+				tableref,
+				quoteIdentifier(name),
+				quoteIdentifier(relation+"_"+name),
+			))
 		}
 	}
 	return joined, selected, nil
@@ -174,46 +336,222 @@ FieldScan:
 // HasNFieldsFor queries related model to build JOIN and SELECTs query substrings SQL query buildders can use directly.
 // It uses a callback, which can provide a *FieldList from the referenced type.
 func (fl *FieldList) HasNFieldsFor(relation, tableref string, field FieldListItem, typeMapper func(reflect.Type) *FieldList) ([]string, []string, error) {
-	var joined []string
+	joined, selected, err := fl.hasNJoins(relation, tableref, field, typeMapper)
+	return joinStrings(joined), selected, err
+}
+
+// hasNJoins builds JOIN clauses and SELECTs for a "has one," "has many,"
+// "many to many," or "has many through" relation. The first clause's
+// condition references the model.
+func (fl *FieldList) hasNJoins(relation, tableref string, field FieldListItem, typeMapper func(reflect.Type) *FieldList) ([]joinClause, []string, error) {
+	var joined []joinClause
 	relindex, hasRelIndex := field.Options[OptRelation]
 	revindex, hasRevIndex := field.Options[OptReverse]
 	throughTable, hasThrough := field.Options[OptThrough]
+	poly, hasPoly := field.Options[OptPolymorphic]
 
-	if !hasRelIndex {
+	if via, hasVia := field.Options[OptVia]; hasVia {
+		if !hasThrough {
+			return nil, nil, errors.Errorf("relation %q has no intermediate relation", relation)
+		}
+		return fl.throughModelJoins(relation, tableref, field, throughTable, via, typeMapper)
+	}
+	if !hasRelIndex && !hasPoly {
 		return nil, nil, errors.New("not a relation")
 	}
+	if !hasRelIndex {
+		relindex = poly
+	}
 
 	t := deref(field.Type)
 	if t.Kind() == reflect.Slice {
 		t = t.Elem()
 	}
-	tablename, err := tableNameByType(t)
+	tablename, err := fl.tableNameByType(t)
 	if err != nil {
 		return nil, nil, err
 	}
 	if hasRevIndex && hasThrough {
 		joined = append(
 			joined,
-			fmt.Sprintf("%s t%s ON (t1.id=t%s.%s_id)", throughTable, tableref, tableref, relindex),
-			fmt.Sprintf("%s %s ON (%s.id=t%s.%s_id)", tablename, tableref, tableref, tableref, revindex),
+			joinClause{
+				table: quoteIdentifier(throughTable) + " t" + tableref,
+				on:    fmt.Sprintf("t1.id=t%s.%s", tableref, quoteIdentifier(relindex+"_id")),
+			},
+			joinClause{
+				table: quoteIdentifier(tablename) + " " + tableref,
+				on:    fmt.Sprintf("%s.id=t%s.%s", tableref, tableref, quoteIdentifier(revindex+"_id")),
+			},
 		)
 	} else {
-		joined = append(joined, fmt.Sprintf("%s %s ON (t1.id=%s.%s_id)", tablename, tableref, tableref, relindex))
+		extra := ""
+		if hasPoly {
+			polytype, err := fl.polymorphicType(fl.Type, field)
+			if err != nil {
+				return nil, nil, err
+			}
+			extra = fmt.Sprintf(" AND %s.%s=%s", tableref, quoteIdentifier(poly+"_type"), quoteLiteral(polytype))
+		}
+		joined = append(joined, joinClause{
+			table: quoteIdentifier(tablename) + " " + tableref,
+			on:    fmt.Sprintf("t1.id=%s.%s%s", tableref, quoteIdentifier(relindex+"_id"), extra),
+		})
 	}
 	flSub := typeMapper(t)
-	fields, err := flSub.FieldsFor()
+	selected, err := joinedFields(flSub, relation, tableref)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(fl.Joins) == 0 {
+		fl.Joins = map[string]*FieldList{}
+	}
+	fl.Joins[relation] = flSub
+	return joined, selected, nil
+}
+
+// throughModel describes the intermediate model of a "has many through"
+// relation
+type throughModel struct {
+	// field is the model's "has many" relation to the intermediate model
+	field FieldListItem
+	// fl is the intermediate model's field list
+	fl *FieldList
+	// relindex is the intermediate model's reference stub for the model
+	relindex string
+	// via is the intermediate model's "belongs to" relation to the joined
+	// model
+	via string
+}
+
+// throughModel resolves a "has many through" relation's intermediate model
+func (fl *FieldList) throughModel(field FieldListItem, through, via string, typeMapper func(reflect.Type) *FieldList) (*throughModel, error) {
+	throughField, err := fl.relationField(through)
+	if err != nil {
+		return nil, err
+	}
+	relindex, ok := throughField.Options[OptRelation]
+	if _, isM2M := throughField.Options[OptThrough]; !ok || isM2M {
+		return nil, errors.Errorf("relation %q is not a \"has many\" relation", through)
+	}
+	linkType := deref(throughField.Type)
+	if linkType.Kind() == reflect.Slice {
+		linkType = deref(linkType.Elem())
+	}
+	flLink := typeMapper(linkType)
+	viaField, err := flLink.relationField(via)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := viaField.Options[OptBelongs]; !ok {
+		return nil, errors.Errorf("relation %q is not a \"belongs to\" relation", via)
+	}
+	t := deref(field.Type)
+	if t.Kind() == reflect.Slice {
+		t = deref(t.Elem())
+	}
+	if deref(viaField.Type) != t {
+		return nil, errors.Errorf("relation %q doesn't reference %s", via, t)
+	}
+	return &throughModel{field: throughField, fl: flLink, relindex: relindex, via: via}, nil
+}
+
+// throughModelJoins builds JOIN clauses and SELECTs for a "has many
+// through" relation, joining the intermediate model through the model's
+// "has many" relation (through), and then the joined model through the
+// intermediate model's "belongs to" relation (via).
+func (fl *FieldList) throughModelJoins(relation, tableref string, field FieldListItem, through, via string, typeMapper func(reflect.Type) *FieldList) ([]joinClause, []string, error) {
+	link, err := fl.throughModel(field, through, via, typeMapper)
+	if err != nil {
+		return nil, nil, err
+	}
+	t := deref(field.Type)
+	if t.Kind() == reflect.Slice {
+		t = deref(t.Elem())
+	}
+	linkTable, err := fl.tableNameByType(link.fl.Type)
+	if err != nil {
+		return nil, nil, err
+	}
+	tablename, err := fl.tableNameByType(t)
 	if err != nil {
 		return nil, nil, err
 	}
+	joined := []joinClause{
+		{
+			table: quoteIdentifier(linkTable) + " t" + tableref,
+			on:    fmt.Sprintf("t1.id=t%s.%s", tableref, quoteIdentifier(link.relindex+"_id")),
+		},
+		{
+			table: quoteIdentifier(tablename) + " " + tableref,
+			on:    fmt.Sprintf("%s.id=t%s.%s", tableref, tableref, quoteIdentifier(link.via+"_id")),
+		},
+	}
 	if len(fl.Joins) == 0 {
 		fl.Joins = map[string]*FieldList{}
 	}
+	flSub := typeMapper(t)
+	selected, err := joinedFields(flSub, relation, tableref)
+	if err != nil {
+		return nil, nil, err
+	}
 	fl.Joins[relation] = flSub
+	if _, ok := field.Options[OptLinks]; ok {
+		links, err := joinedFields(link.fl, link.field.Path, "t"+tableref)
+		if err != nil {
+			return nil, nil, err
+		}
+		selected = append(selected, links...)
+		fl.Joins[link.field.Path] = link.fl
+	}
+	return joined, selected, nil
+}
+
+// joinedFields returns SELECTs of a joined model's columns, prefixed with
+// the relation's name.
+func joinedFields(fl *FieldList, relation, tableref string) ([]string, error) {
+	fields, err := fl.FieldsFor()
+	if err != nil {
+		return nil, err
+	}
 	selected := make([]string, 0, len(fields))
 	for _, field := range fields {
-		selected = append(selected, fmt.Sprintf("%s.%s AS %s_%s", tableref, field.key, relation, field.key))
+		selected = append(selected, fmt.Sprintf(
+			"%s.%s AS %s",
+			tableref,
+			quoteIdentifier(field.key),
+			quoteIdentifier(relation+"_"+field.key),
+		))
+	}
+	return selected, nil
+}
+
+func (fl *FieldList) tableNameByType(t reflect.Type) (string, error) {
+	if fl.mapper == nil {
+		return (&Mapper{}).tableNameByType(t)
+	}
+	return fl.mapper.tableNameByType(t)
+}
+
+// JoinAll prepares the field list for traversing columns of all "has many"
+// and "many to many" relations, without building JOIN clauses. Columns of
+// these relations are expected to be prefixed with the relation's name,
+// just like the ones HasNFieldsFor selects.
+func (fl *FieldList) JoinAll(typeMapper func(reflect.Type) *FieldList) {
+	for _, field := range fl.Fields {
+		if !isHasN(field) {
+			continue
+		}
+		t := deref(field.Type)
+		if t.Kind() != reflect.Slice {
+			continue
+		}
+		if len(fl.Joins) == 0 {
+			fl.Joins = map[string]*FieldList{}
+		}
+		if _, ok := fl.Joins[field.Path]; !ok {
+			fl.Joins[field.Path] = typeMapper(t.Elem())
+		}
 	}
-	return joined, selected, nil
 }
 
 // TraversalsByName provides a traversal index for SELECT query results, to map result rows' columns with model's entry positions
@@ -230,18 +568,14 @@ func (fl *FieldList) TraversalsByName(columns []string) (Traversals, error) {
 }
 
 func (fl *FieldList) traversalByName(column, prefix string, parentIndex []int) *Traversal {
-	subcol := strings.SplitN(column, "_", 2)
-	if len(parentIndex) < 1 {
-		parentIndex = []int{}
-	}
 	for idx, fi := range fl.Fields {
 		if fi.Traversed {
 			continue
 		}
 		if fi.Path == column {
 			fl.Fields[idx].Traversed = true
-			parentIndex = append(parentIndex, fi.Index...)
-			trav := &Traversal{Name: prefix + column, Index: parentIndex}
+			index := append(append([]int{}, parentIndex...), fi.Index...)
+			trav := &Traversal{Name: prefix + column, Index: index, typ: fi.Type}
 			if relation, ok := fi.Options[OptRelatedTo]; ok {
 				for _, item := range fl.Fields {
 					if item.Name == relation {
@@ -249,37 +583,58 @@ func (fl *FieldList) traversalByName(column, prefix string, parentIndex []int) *
 					}
 				}
 			}
-			return trav
-		}
-		if len(subcol) > 1 && fi.Path == subcol[0] {
-			otherfl, ok := fl.Joins[subcol[0]]
-			if !ok {
-				continue
-			}
-			trav := otherfl.traversalByName(subcol[1], subcol[0]+"_", fi.Index)
-			if trav == nil {
-				continue
+			if fl.isRelated(fi) {
+				trav.relDepth = len(parentIndex) + 1
 			}
 			return trav
 		}
+		if !strings.HasPrefix(column, fi.Path+"_") {
+			continue
+		}
+		otherfl, ok := fl.Joins[fi.Path]
+		if !ok {
+			continue
+		}
+		index := append(append([]int{}, parentIndex...), fi.Index...)
+		trav := otherfl.traversalByName(column[len(fi.Path)+1:], prefix+fi.Path+"_", index)
+		if trav == nil {
+			continue
+		}
+		trav.relDepth = len(index)
+		return trav
 	}
 	return nil
 }
 
+// isRelated checks whether a field is a subfield of a relation (or "belongs
+// to") field, by checking its top level field.
+func (fl *FieldList) isRelated(fi FieldListItem) bool {
+	if len(fi.Index) < 2 {
+		return false
+	}
+	for _, item := range fl.Fields {
+		if len(item.Index) != 1 || item.Index[0] != fi.Index[0] {
+			continue
+		}
+		return isRelation(item)
+	}
+	return false
+}
+
 // QField returns a query field based on a FieldListItem
 func (fi FieldListItem) QField() *QueryField {
 	val := ":" + fi.Path
-	for _, opt := range []string{"relation", "belongs"} {
-		_, ok := fi.Options[opt]
-		if ok {
-			return nil
-		}
+	if isRelation(fi) {
+		return nil
+	}
+	if _, ok := fi.Options[OptReadOnly]; ok {
+		return nil
 	}
 	return &QueryField{
 		key:  fi.Path,
 		opts: fi.Options,
 		val:  val,
-		eq:   fmt.Sprintf("%s=%s", fi.Path, val),
+		eq:   fmt.Sprintf("%s=%s", quoteIdentifier(fi.Path), val),
 	}
 }
 
@@ -302,3 +657,49 @@ func (t Traversals) Map(v reflect.Value, values []interface{}) error {
 	}
 	return nil
 }
+
+// mapRelated is like Map, but it fills values slice with temporary pointers
+// for related models' columns, instead of allocating related models. The
+// returned function has to be called after scanning: it moves the scanned
+// values into the model, allocating a related model only if at least one of
+// its columns is not NULL. This way, LEFT JOINs without a matching row leave
+// relations empty.
+func (t Traversals) mapRelated(v reflect.Value, values []interface{}) (func(), error) {
+	v = reflect.Indirect(v)
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("argument not a struct")
+	}
+	holders := map[int]reflect.Value{}
+	for i, traversal := range t {
+		switch {
+		case len(traversal.Index) == 0:
+			values[i] = new(interface{})
+		case traversal.relDepth > 0 && traversal.typ != nil:
+			holder := reflect.New(reflect.PtrTo(traversal.typ))
+			holders[i] = holder
+			values[i] = holder.Interface()
+		default:
+			values[i] = fieldByIndexes(v, traversal.Index).Addr().Interface()
+		}
+	}
+	return func() {
+		present := map[string]bool{}
+		for i, holder := range holders {
+			if !holder.Elem().IsNil() {
+				present[t[i].relationKey()] = true
+			}
+		}
+		for i, traversal := range t {
+			holder, ok := holders[i]
+			if !ok || !present[traversal.relationKey()] || holder.Elem().IsNil() {
+				continue
+			}
+			fieldByIndexes(v, traversal.Index).Set(holder.Elem().Elem())
+		}
+	}, nil
+}
+
+// relationKey identifies the related model a traversal points into
+func (t *Traversal) relationKey() string {
+	return fmt.Sprint(t.Index[:t.relDepth])
+}
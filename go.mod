@@ -1,6 +1,6 @@
 module github.com/julian7/dmpr
 
-go 1.12
+go 1.16
 
 require (
 	github.com/DATA-DOG/go-sqlmock v1.3.3
@@ -0,0 +1,156 @@
+package dmpr
+
+import (
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+)
+
+// ErrStopIteration can be returned by Each's callback to stop iterating
+// without an error.
+var ErrStopIteration = errors.New("stop iteration")
+
+// Iterator is a cursor over a SELECT query's results, which scans rows one
+// by one, instead of loading all of them into memory. Consecutive rows of
+// the same model (by its "id" column) are merged into one model, collecting
+// their "has many" relations. Therefore, when joining "has many" or "many
+// to many" relations, results must be ordered by the model's ID.
+//
+// Iterator must be closed after use.
+type Iterator struct {
+	rows    *sqlx.Rows
+	scanner *rowScanner
+	current reflect.Value
+	next    reflect.Value
+	nextID  *int
+	err     error
+	done    bool
+}
+
+// Iter executes SELECT query, returning an iterator over its results. If
+// the query joins relations, and no order is set, results are ordered by
+// the model's ID, to keep rows of the same model together.
+func (q *SelectQuery) Iter() (*Iterator, error) {
+	if q.recursive != nil {
+		return nil, errors.New("Recursive queries cannot be iterated")
+	}
+	t, _ := Reflect(q.model)
+	fl := q.mapper.FieldList(t)
+
+	sel := *q
+	if len(sel.incl) > 0 && len(sel.order) == 0 {
+		sel.order = []orderItem{{column: "id"}}
+	}
+	query, args, err := sel.allSelector(fl)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := q.mapper.Queryx(query, args...)
+	if err != nil {
+		return nil, errors.Wrap(err, "Iter query")
+	}
+	scanner, err := newRowScanner(rows, fl)
+	if err != nil {
+		rows.Close()
+		return nil, errors.Wrap(err, "Iter")
+	}
+	return &Iterator{rows: rows, scanner: scanner}, nil
+}
+
+// Each executes SELECT query, calling cb with each model (a pointer of the
+// model type) one by one. Iteration stops at the first error cb returns,
+// and Each returns that error, except for ErrStopIteration, which stops
+// iteration silently.
+func (q *SelectQuery) Each(cb func(model interface{}) error) error {
+	iter, err := q.Iter()
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+	for iter.Next() {
+		if err := cb(iter.Model()); err != nil {
+			if err == ErrStopIteration {
+				return nil
+			}
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+// Next prepares the next model for reading with Model. It returns false
+// if there are no more models, or an error happened. In both cases, the
+// iterator is closed.
+func (it *Iterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if !it.next.IsValid() && !it.advance() {
+		it.current = reflect.Value{}
+		it.Close()
+		return false
+	}
+	it.current = it.next
+	currentID := it.nextID
+	it.next = reflect.Value{}
+	for it.advance() {
+		if currentID == nil || it.nextID == nil || *currentID != *it.nextID {
+			return true
+		}
+		if _, err := mergeFields(it.current, it.next); err != nil {
+			it.err = errors.Wrap(err, "Iter merging fields")
+			it.Close()
+			return false
+		}
+		it.next = reflect.Value{}
+	}
+	if it.err != nil {
+		it.current = reflect.Value{}
+		it.Close()
+		return false
+	}
+	return true
+}
+
+// advance reads the next row into it.next
+func (it *Iterator) advance() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	vp, id, err := it.scanner.scan()
+	if err != nil {
+		it.err = errors.Wrap(err, "Iter")
+		return false
+	}
+	it.next = vp
+	it.nextID = id
+	return true
+}
+
+// Model returns the current model as a pointer of the model type
+func (it *Iterator) Model() interface{} {
+	if !it.current.IsValid() {
+		return nil
+	}
+	return it.current.Interface()
+}
+
+// Err returns the error happened during iteration, if any
+func (it *Iterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+// Close closes the iterator, releasing its database resources. It is safe
+// to call Close multiple times.
+func (it *Iterator) Close() error {
+	if it.done {
+		return nil
+	}
+	it.done = true
+	it.next = reflect.Value{}
+	return it.rows.Close()
+}
@@ -0,0 +1,171 @@
+package dmpr_test
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/dmpr"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+)
+
+func TestSelectQuery_Each(t *testing.T) {
+	hasManyQuery := fmt.Sprintf("^%s$", regexp.QuoteMeta(
+		`SELECT t1.id, t1.name, t2.id AS belongs_id, t2.name AS belongs_name, `+
+			`t2.extras AS belongs_extras, t2.one_id AS belongs_one_id, t2.more_id AS belongs_more_id `+
+			`FROM example_has_manies t1 LEFT JOIN example_belongs_toes t2 ON (t1.id=t2.many_id) ORDER BY t1.id`,
+	))
+	hasManyRows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name", "belongs_id", "belongs_name", "belongs_extras", "belongs_one_id", "belongs_more_id"}).
+			AddRow(1, "test", 2, "subname", nil, 0, 1).
+			AddRow(1, "test", 3, "subname2", nil, 0, 1).
+			AddRow(4, "test2", 5, "subname3", nil, 0, 4)
+	}
+	tests := []struct {
+		name     string
+		model    interface{}
+		prep     func(*dmpr.SelectQuery)
+		mock     func(sqlmock.Sqlmock)
+		cb       func(model interface{}) error
+		expected []interface{}
+		err      error
+	}{
+		{
+			name:  "plain rows",
+			model: &[]ExampleBelongsTo{},
+			prep:  func(s *dmpr.SelectQuery) { s.Select("id", "name") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name"}).
+					AddRow(1, "test").
+					AddRow(2, "test2")
+				mock.ExpectQuery(`^SELECT t1\.id, t1\.name FROM example_belongs_toes t1$`).WillReturnRows(rows).RowsWillBeClosed()
+			},
+			expected: []interface{}{
+				&ExampleBelongsTo{ID: 1, Name: "test"},
+				&ExampleBelongsTo{ID: 2, Name: "test2"},
+			},
+		},
+		{
+			name:  "grouping has many rows",
+			model: &[]ExampleHasMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("belongs") },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(hasManyQuery).WillReturnRows(hasManyRows()).RowsWillBeClosed()
+			},
+			expected: []interface{}{
+				&ExampleHasMany{ID: 1, Name: "test", Belongs: []*ExampleBelongsTo{
+					{ID: 2, Name: "subname", MoreID: 1},
+					{ID: 3, Name: "subname2", MoreID: 1},
+				}},
+				&ExampleHasMany{ID: 4, Name: "test2", Belongs: []*ExampleBelongsTo{
+					{ID: 5, Name: "subname3", MoreID: 4},
+				}},
+			},
+		},
+		{
+			name:  "early termination",
+			model: &[]ExampleHasMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("belongs") },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(hasManyQuery).WillReturnRows(hasManyRows()).RowsWillBeClosed()
+			},
+			cb: func(model interface{}) error { return dmpr.ErrStopIteration },
+			expected: []interface{}{
+				&ExampleHasMany{ID: 1, Name: "test", Belongs: []*ExampleBelongsTo{
+					{ID: 2, Name: "subname", MoreID: 1},
+					{ID: 3, Name: "subname2", MoreID: 1},
+				}},
+			},
+		},
+		{
+			name:  "callback error",
+			model: &[]ExampleHasMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("belongs") },
+			mock: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(hasManyQuery).WillReturnRows(hasManyRows()).RowsWillBeClosed()
+			},
+			cb:  func(model interface{}) error { return errors.New("failed") },
+			err: errors.New("failed"),
+			expected: []interface{}{
+				&ExampleHasMany{ID: 1, Name: "test", Belongs: []*ExampleBelongsTo{
+					{ID: 2, Name: "subname", MoreID: 1},
+					{ID: 3, Name: "subname2", MoreID: 1},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			mapper := dmpr.New("")
+			mapper.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
+			q, err := mapper.NewSelect(tt.model)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.prep != nil {
+				tt.prep(q)
+			}
+			if tt.mock != nil {
+				tt.mock(mock)
+			}
+			received := []interface{}{}
+			err = q.Each(func(model interface{}) error {
+				received = append(received, model)
+				if tt.cb != nil {
+					return tt.cb(model)
+				}
+				return nil
+			})
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(received, tt.expected) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, received)
+			}
+		})
+	}
+}
+
+func TestSelectQuery_Iter_scanError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	mapper := dmpr.New("")
+	mapper.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
+	rows := sqlmock.NewRows([]string{"id", "name", "belongs_id", "belongs_name", "belongs_extras", "belongs_one_id", "belongs_more_id"}).
+		AddRow(1, "test", 2, "subname", nil, 0, 1).
+		AddRow(1, "test", "invalid", "subname2", nil, 0, 1)
+	mock.ExpectQuery(`^SELECT t1\.id`).WillReturnRows(rows).RowsWillBeClosed()
+	q, err := mapper.NewSelect(&[]ExampleHasMany{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iter, err := q.Join("belongs").Iter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// no deferred Close: Next has to close the iterator on errors
+	for iter.Next() {
+		t.Errorf("unexpected model: %+v", iter.Model())
+	}
+	if iter.Err() == nil {
+		t.Error("scan error is not reported")
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,101 @@
+package dmpr
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// JoinOption is an option of JoinLimited
+type JoinOption func(*joinOptions)
+
+type joinOptions struct {
+	order []orderItem
+	limit int
+}
+
+// OrderBy sorts related models of JoinLimited by columns. Unqualified
+// columns reference the relation's table, and they can have an " ASC" or
+// " DESC" suffix, just like in SelectQuery.OrderBy.
+func OrderBy(columns ...string) JoinOption {
+	return func(opts *joinOptions) {
+		for _, column := range columns {
+			opts.order = append(opts.order, parseOrderItem(column))
+		}
+	}
+}
+
+// Limit limits the number of related models of JoinLimited for each model
+func Limit(limit int) JoinOption {
+	return func(opts *joinOptions) {
+		opts.limit = limit
+	}
+}
+
+// JoinLimited joins a "has many", "many-to-many", or "has many through"
+// relation like Join, but only the first related models of each model are
+// joined, by a LATERAL subquery. Example:
+//
+// ```golang
+// // posts with their 3 newest comments
+// query.JoinLimited("comments", dmpr.OrderBy("created_at DESC"), dmpr.Limit(3))
+// ```
+//
+// It generates `LEFT JOIN LATERAL (SELECT t2.* FROM comments t2 WHERE
+// t1.id=t2.post_id ORDER BY t2.created_at DESC LIMIT 3) t2 ON true`.
+func (q *SelectQuery) JoinLimited(relation string, options ...JoinOption) *SelectQuery {
+	if q.limit == nil {
+		q.limit = map[string]*joinOptions{}
+	}
+	opts := &joinOptions{}
+	for _, opt := range options {
+		opt(opts)
+	}
+	q.include(relation)
+	q.limit[relation] = opts
+	return q
+}
+
+// lateral converts JOIN clauses of a relation into a single LATERAL
+// subquery, applying order and limit of opts. The first clause's condition
+// references the model, which becomes the subquery's WHERE clause.
+func (q *SelectQuery) lateral(fl *FieldList, relation, tableref string, joining []joinClause, opts *joinOptions) ([]string, error) {
+	field, err := fl.relationField(relation)
+	if err != nil {
+		return nil, err
+	}
+	if !isHasN(field) {
+		return nil, errors.Errorf("relation %q is not a \"has many\" relation", relation)
+	}
+	if _, ok := field.Options[OptLinks]; ok {
+		return nil, errors.Errorf("relation %q with links cannot be limited", relation)
+	}
+	if len(joining) < 1 {
+		return nil, nil
+	}
+	var sub strings.Builder
+	fmt.Fprintf(&sub, "SELECT %s.* FROM %s", tableref, joining[0].table)
+	for _, clause := range joining[1:] {
+		sub.WriteString(" JOIN " + clause.String())
+	}
+	sub.WriteString(" WHERE " + joining[0].on)
+	if len(opts.order) > 0 {
+		order := make([]string, 0, len(opts.order))
+		for _, item := range opts.order {
+			expr, err := q.resolveColumnIn(fl, item.column, tableref)
+			if err != nil {
+				return nil, err
+			}
+			if item.desc {
+				expr += " DESC"
+			}
+			order = append(order, expr)
+		}
+		sub.WriteString(" ORDER BY " + strings.Join(order, ", "))
+	}
+	if opts.limit > 0 {
+		fmt.Fprintf(&sub, " LIMIT %d", opts.limit)
+	}
+	return []string{fmt.Sprintf("LATERAL (%s) %s ON true", sub.String(), tableref)}, nil
+}
@@ -0,0 +1,273 @@
+package dmpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const loadKey = "dmpr_key"
+
+// loadPlan describes how a relation of already fetched models is loaded
+type loadPlan struct {
+	field  FieldListItem
+	target reflect.Type
+	// alias is the related table's alias
+	alias string
+	// join is an optional JOIN clause for a linker table or intermediate
+	// model
+	join string
+	// key is the expression matched against owners' keys
+	key string
+	// extra is an extra condition of the WHERE clause
+	extra string
+	// ownerKey is the index of owners' field, which is matched by key
+	ownerKey []int
+	// ownerColumn is the column of ownerKey
+	ownerColumn string
+	// polyIndex and polyType filter owners of a polymorphic "belongs to"
+	// relation by their type column
+	polyIndex []int
+	polyType  string
+}
+
+// Load fills relations of a model already in memory (a pointer of a
+// struct), using the relations' struct tags, just like SelectQuery.Join.
+// Relations are referenced by their names. Example:
+//
+// ```golang
+// user := &User{}
+// err := mapper.Find(user, 1)
+// err = mapper.Load(user, "profile", "to_do_items")
+// ```
+//
+// Relations' own relations are not filled.
+func (m *Mapper) Load(model interface{}, relations ...string) error {
+	value := reflect.ValueOf(model)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Struct {
+		return errors.New("pointer of a struct is expected for Load")
+	}
+	models := reflect.Append(reflect.MakeSlice(reflect.SliceOf(value.Type()), 0, 1), value)
+	return m.load(models, relations)
+}
+
+// LoadAll fills relations of a slice of models already in memory (a pointer
+// of a slice of structs, or pointers of structs), like Load. It runs one
+// query for each relation, for all the models.
+func (m *Mapper) LoadAll(models interface{}, relations ...string) error {
+	value := reflect.ValueOf(models)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Slice {
+		return errors.New("pointer of a slice is expected for LoadAll")
+	}
+	return m.load(value.Elem(), relations)
+}
+
+func (m *Mapper) load(models reflect.Value, relations []string) error {
+	t := deref(models.Type().Elem())
+	if t.Kind() != reflect.Struct {
+		return ErrInvalidType
+	}
+	fl := m.FieldList(t)
+	if fl == nil {
+		return errors.New("cannot get field list")
+	}
+	owners := make([]reflect.Value, 0, models.Len())
+	for idx := 0; idx < models.Len(); idx++ {
+		owner := models.Index(idx)
+		if owner.Kind() == reflect.Ptr {
+			if owner.IsNil() {
+				continue
+			}
+			owner = owner.Elem()
+		}
+		owners = append(owners, owner)
+	}
+	for _, relation := range relations {
+		plan, err := m.loadPlan(fl, relation, "t1", "tt")
+		if err != nil {
+			return err
+		}
+		if err := m.loadRelation(plan, owners); err != nil {
+			return errors.Wrapf(err, "loading %q", relation)
+		}
+	}
+	return nil
+}
+
+// loadPlan describes how a relation is loaded, with the related table
+// aliased as alias, and the linker table (or intermediate model) aliased as
+// linkAlias.
+func (m *Mapper) loadPlan(fl *FieldList, relation, alias, linkAlias string) (*loadPlan, error) {
+	field, err := fl.relationField(relation)
+	if err != nil {
+		return nil, err
+	}
+	t := deref(field.Type)
+	if t.Kind() == reflect.Slice {
+		t = deref(t.Elem())
+	}
+	plan := &loadPlan{field: field, target: t, alias: alias, key: alias + ".id"}
+	ownerKey := "id"
+	relindex, hasRelIndex := field.Options[OptRelation]
+	revindex, hasRevIndex := field.Options[OptReverse]
+	through, hasThrough := field.Options[OptThrough]
+	poly, hasPoly := field.Options[OptPolymorphic]
+	via, hasVia := field.Options[OptVia]
+
+	switch _, isBelongs := field.Options[OptBelongs]; {
+	case isBelongs:
+		ownerKey = field.Path + "_id"
+		if hasPoly {
+			ownerKey = poly + "_id"
+			polyIndex, ok := fl.columnIndex(poly + "_type")
+			if !ok {
+				return nil, &UnknownColumnError{Model: fl.Type, Column: poly + "_type"}
+			}
+			if plan.polyType, err = fl.polymorphicType(t, field); err != nil {
+				return nil, err
+			}
+			plan.polyIndex = polyIndex
+		}
+	case hasVia && hasThrough:
+		link, err := fl.throughModel(field, through, via, m.FieldList)
+		if err != nil {
+			return nil, err
+		}
+		linkTable, err := m.tableNameByType(link.fl.Type)
+		if err != nil {
+			return nil, err
+		}
+		plan.join = fmt.Sprintf(" JOIN %s %s ON (%s.id=%s.%s)", quoteIdentifier(linkTable), linkAlias, alias, linkAlias, quoteIdentifier(via+"_id"))
+		plan.key = linkAlias + "." + quoteIdentifier(link.relindex+"_id")
+	case hasRelIndex && hasRevIndex && hasThrough:
+		plan.join = fmt.Sprintf(" JOIN %s %s ON (%s.id=%s.%s)", quoteIdentifier(through), linkAlias, alias, linkAlias, quoteIdentifier(revindex+"_id"))
+		plan.key = linkAlias + "." + quoteIdentifier(relindex+"_id")
+	case hasRelIndex:
+		plan.key = alias + "." + quoteIdentifier(relindex+"_id")
+	case hasPoly:
+		plan.key = alias + "." + quoteIdentifier(poly+"_id")
+		polyType, err := fl.polymorphicType(fl.Type, field)
+		if err != nil {
+			return nil, err
+		}
+		plan.extra = fmt.Sprintf(" AND %s.%s=%s", alias, quoteIdentifier(poly+"_type"), quoteLiteral(polyType))
+	default:
+		return nil, errors.Errorf("Relation %q not found", relation)
+	}
+	index, ok := fl.columnIndex(ownerKey)
+	if !ok {
+		return nil, &UnknownColumnError{Model: fl.Type, Column: ownerKey}
+	}
+	plan.ownerKey = index
+	plan.ownerColumn = ownerKey
+	return plan, nil
+}
+
+// loadBatchSize is the maximum number of owner keys queried at once, well
+// below PostgreSQL's limit of 65535 bind parameters
+var loadBatchSize = 1000
+
+// loadRelation queries related models of owners by plan, in batches of
+// owner keys, and sets them in owners' relation field.
+func (m *Mapper) loadRelation(plan *loadPlan, owners []reflect.Value) error {
+	args := []interface{}{}
+	keys := map[string]bool{}
+	targets := make([]reflect.Value, 0, len(owners))
+	for _, owner := range owners {
+		if plan.polyIndex != nil && keyValue(owner.FieldByIndex(plan.polyIndex)) != plan.polyType {
+			continue
+		}
+		key := owner.FieldByIndex(plan.ownerKey)
+		field := owner.FieldByIndex(plan.field.Index)
+		field.Set(reflect.Zero(field.Type()))
+		arg := keyArg(key)
+		if arg == nil || isEmptyValue(key) {
+			continue
+		}
+		targets = append(targets, owner)
+		if keys[keyValue(key)] {
+			continue
+		}
+		keys[keyValue(key)] = true
+		args = append(args, arg)
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	table, err := m.tableNameByType(plan.target)
+	if err != nil {
+		return err
+	}
+	fl := m.FieldList(plan.target)
+	if fl == nil {
+		return errors.New("cannot get field list")
+	}
+	fields, err := fl.FieldsFor()
+	if err != nil {
+		return err
+	}
+	selected := make([]string, 0, len(fields)+1)
+	for _, item := range fields {
+		selected = append(selected, plan.alias+"."+quoteIdentifier(item.key))
+	}
+	selected = append(selected, plan.key+" AS "+loadKey)
+	byKey := map[string][]reflect.Value{}
+	for start := 0; start < len(args); start += loadBatchSize {
+		end := start + loadBatchSize
+		if end > len(args) {
+			end = len(args)
+		}
+		placeholders := make([]string, 0, end-start)
+		for idx := range args[start:end] {
+			placeholders = append(placeholders, fmt.Sprintf("$%d", idx+1))
+		}
+		query := fmt.Sprintf(
+			"SELECT %s FROM %s %s%s WHERE %s IN (%s)%s",
+			strings.Join(selected, ", "),
+			quoteIdentifier(table),
+			plan.alias,
+			plan.join,
+			plan.key,
+			strings.Join(placeholders, ", "),
+			plan.extra,
+		)
+		if err := m.loadBatch(query, args[start:end], plan.target, byKey); err != nil {
+			return err
+		}
+	}
+	for _, owner := range targets {
+		field := owner.FieldByIndex(plan.field.Index)
+		for _, vp := range byKey[keyValue(owner.FieldByIndex(plan.ownerKey))] {
+			if err := attachNode(field, vp); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadBatch runs a query of loadRelation, collecting related models by
+// their owners' keys. Each batch needs a field list of its own, as
+// scanning marks its fields traversed.
+func (m *Mapper) loadBatch(query string, args []interface{}, t reflect.Type, byKey map[string][]reflect.Value) error {
+	fl := m.FieldList(t)
+	if fl == nil {
+		return errors.New("cannot get field list")
+	}
+	rows, err := m.Queryx(query, args...)
+	if err != nil {
+		return errors.Wrap(err, "Load query")
+	}
+	defer rows.Close()
+	related, relatedKeys, err := scanWithExtra(rows, fl, loadKey)
+	if err != nil {
+		return errors.Wrap(err, "Load")
+	}
+	for idx, vp := range related {
+		key := keyValue(reflect.ValueOf(relatedKeys[idx]))
+		byKey[key] = append(byKey[key], vp)
+	}
+	return nil
+}
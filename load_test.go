@@ -0,0 +1,202 @@
+package dmpr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/guregu/null.v3"
+)
+
+func TestMapper_Load(t *testing.T) {
+	tests := []struct {
+		name     string
+		mocks    []func(sqlmock.Sqlmock)
+		model    interface{}
+		call     func(*Mapper, interface{}) error
+		expected interface{}
+		err      error
+	}{
+		{
+			name: "has many",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "title", "author_id", "dmpr_key"}).
+						AddRow(2, "first", 1, 1).
+						AddRow(3, "second", 1, 1)
+					mock.ExpectQuery("^SELECT t1\\.id, t1\\.title, t1\\.author_id, t1\\.author_id AS dmpr_key " +
+						"FROM example_posts t1 WHERE t1\\.author_id IN \\(\\$1\\)$").
+						WithArgs(1).WillReturnRows(rows)
+				},
+			},
+			model: &ExampleAuthor{ID: 1, Name: "author", Posts: []*ExamplePost{{ID: 9}}},
+			call: func(m *Mapper, model interface{}) error {
+				return m.Load(model, "posts")
+			},
+			expected: &ExampleAuthor{ID: 1, Name: "author", Posts: []*ExamplePost{
+				{ID: 2, Title: "first", AuthorID: 1},
+				{ID: 3, Title: "second", AuthorID: 1},
+			}},
+		},
+		{
+			name: "belongs to and has many values in a slice",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "name", "dmpr_key"}).
+						AddRow(5, "account", 5)
+					mock.ExpectQuery("^SELECT t1\\.id, t1\\.name, t1\\.id AS dmpr_key " +
+						"FROM example_accounts t1 WHERE t1\\.id IN \\(\\$1\\)$").
+						WithArgs(5).WillReturnRows(rows)
+				},
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "title", "author_id", "dmpr_key"}).
+						AddRow(2, "first", 1, 1).
+						AddRow(3, "second", 3, 3)
+					mock.ExpectQuery("^SELECT t1\\.id, t1\\.title, t1\\.author_id, t1\\.author_id AS dmpr_key "+
+						"FROM example_posts t1 WHERE t1\\.author_id IN \\(\\$1, \\$2, \\$3\\)$").
+						WithArgs(1, 2, 3).WillReturnRows(rows)
+				},
+			},
+			model: &[]ExampleMember{
+				{ID: 1, AccountID: null.IntFrom(5)},
+				{ID: 2},
+				{ID: 3, AccountID: null.IntFrom(5)},
+			},
+			call: func(m *Mapper, model interface{}) error {
+				return m.LoadAll(model, "account", "posts")
+			},
+			expected: &[]ExampleMember{
+				{
+					ID:        1,
+					AccountID: null.IntFrom(5),
+					Account:   &ExampleAccount{ID: 5, Name: "account"},
+					Posts:     []ExamplePost{{ID: 2, Title: "first", AuthorID: 1}},
+				},
+				{ID: 2},
+				{
+					ID:        3,
+					AccountID: null.IntFrom(5),
+					Account:   &ExampleAccount{ID: 5, Name: "account"},
+					Posts:     []ExamplePost{{ID: 3, Title: "second", AuthorID: 3}},
+				},
+			},
+		},
+		{
+			name: "many to many",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "name", "dmpr_key"}).
+						AddRow(3, "admins", 1).
+						AddRow(3, "admins", 2).
+						AddRow(4, "editors", 2)
+					mock.ExpectQuery("^SELECT t1\\.id, t1\\.name, tt\\.user_id AS dmpr_key "+
+						"FROM example_groups t1 JOIN user_groups tt ON \\(t1\\.id=tt\\.group_id\\) "+
+						"WHERE tt\\.user_id IN \\(\\$1, \\$2\\)$").
+						WithArgs(1, 2).WillReturnRows(rows)
+				},
+			},
+			model: &[]*ExampleUser{{ID: 1}, nil, {ID: 2}},
+			call: func(m *Mapper, model interface{}) error {
+				return m.LoadAll(model, "groups")
+			},
+			expected: &[]*ExampleUser{
+				{ID: 1, Groups: []*ExampleGroup{{ID: 3, Name: "admins"}}},
+				nil,
+				{ID: 2, Groups: []*ExampleGroup{{ID: 3, Name: "admins"}, {ID: 4, Name: "editors"}}},
+			},
+		},
+		{
+			name:  "no keys",
+			model: &ExampleMember{ID: 1},
+			call: func(m *Mapper, model interface{}) error {
+				return m.Load(model, "account")
+			},
+			expected: &ExampleMember{ID: 1},
+		},
+		{
+			name:  "unknown relation",
+			model: &ExampleAuthor{ID: 1},
+			call: func(m *Mapper, model interface{}) error {
+				return m.Load(model, "name")
+			},
+			err: errors.New(`Relation "name" not found`),
+		},
+		{
+			name:  "not a pointer",
+			model: ExampleAuthor{ID: 1},
+			call: func(m *Mapper, model interface{}) error {
+				return m.Load(model, "posts")
+			},
+			err: errors.New("pointer of a struct is expected for Load"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			for _, item := range tt.mocks {
+				item(mock)
+			}
+			mapper := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			err = tt.call(mapper, tt.model)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err != nil {
+				return
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if !reflect.DeepEqual(tt.model, tt.expected) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, tt.model)
+			}
+		})
+	}
+}
+
+func TestMapper_LoadAll_batches(t *testing.T) {
+	defer func(size int) { loadBatchSize = size }(loadBatchSize)
+	loadBatchSize = 2
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	query := "^SELECT t1\\.id, t1\\.title, t1\\.author_id, t1\\.author_id AS dmpr_key FROM example_posts t1 WHERE t1\\.author_id IN "
+	mock.ExpectQuery(query + "\\(\\$1, \\$2\\)$").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "author_id", "dmpr_key"}).AddRow(4, "first", 1, 1))
+	mock.ExpectQuery(query + "\\(\\$1\\)$").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "author_id", "dmpr_key"}).AddRow(5, "second", 3, 3))
+	mapper := &Mapper{
+		Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+		logger: logrus.New(),
+	}
+	authors := []*ExampleAuthor{{ID: 1}, {ID: 2}, {ID: 3}}
+	if err := mapper.LoadAll(&authors, "posts"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	expected := []*ExampleAuthor{
+		{ID: 1, Posts: []*ExamplePost{{ID: 4, Title: "first", AuthorID: 1}}},
+		{ID: 2},
+		{ID: 3, Posts: []*ExamplePost{{ID: 5, Title: "second", AuthorID: 3}}},
+	}
+	if !reflect.DeepEqual(expected, authors) {
+		t.Errorf("results don't match. Expected: %+v\nReceived: %+v", expected, authors)
+	}
+}
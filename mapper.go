@@ -14,18 +14,32 @@ import (
 	_ "github.com/lib/pq"
 )
 
-// Mapper is our PGSQL connection struct
+// Mapper is our PGSQL connection struct. Transaction runs its callback with
+// a copy bound to the transaction, so state shared with the copies is kept
+// behind pointers. Mappers created by New are safe for concurrent use;
+// others set up their shared state on first use.
 type Mapper struct {
-	Conn   *sqlx.DB
-	url    string
-	logger *logrus.Logger
+	Conn       *sqlx.DB
+	url        string
+	logger     *logrus.Logger
+	tables     *tableRegistry
+	metadata   *metadataCache
+	statements *stmtCache
+	tx         *sqlx.Tx
+	checks     *schemaChecks
 }
 
 // New sets up a new SQL connection. It sets up a "black hole" logger too.
 func New(connString string) *Mapper {
 	logger := logrus.New()
 	logger.SetOutput(ioutil.Discard)
-	return &Mapper{url: connString, logger: logger}
+	return &Mapper{
+		url:      connString,
+		logger:   logger,
+		tables:   &tableRegistry{},
+		metadata: &metadataCache{},
+		checks:   &schemaChecks{},
+	}
 }
 
 // Open opens connection to the database. It is implicitly called by
@@ -54,6 +68,45 @@ func (m *Mapper) tryOpen() error {
 	return m.Open()
 }
 
+// Transaction runs fn in a database transaction. Fn receives a mapper bound
+// to the transaction: all queries run through it are part of the
+// transaction. The transaction is committed if fn returns nil, and rolled
+// back otherwise. Calling Transaction on a transaction-bound mapper runs fn
+// in the same transaction.
+func (m *Mapper) Transaction(fn func(tx *Mapper) error) error {
+	if m.tx != nil {
+		return fn(m)
+	}
+	if err := m.tryOpen(); err != nil {
+		return err
+	}
+	m.logger.Debug("DB BEGIN")
+	tx, err := m.Conn.Beginx()
+	if err != nil {
+		return err
+	}
+	txMapper := *m
+	txMapper.tx = tx
+	if err := fn(&txMapper); err != nil {
+		m.logger.Debug("DB ROLLBACK")
+		if rbErr := tx.Rollback(); rbErr != nil {
+			m.logger.Warnf("cannot roll back transaction: %v", rbErr)
+		}
+		return err
+	}
+	m.logger.Debug("DB COMMIT")
+	return tx.Commit()
+}
+
+// ext returns the transaction if the mapper is bound to one, or the
+// database connection otherwise.
+func (m *Mapper) ext() sqlx.Ext {
+	if m.tx != nil {
+		return m.tx
+	}
+	return m.Conn
+}
+
 // Logger sets up internal log method, replacing the discarding logger.
 func (m *Mapper) Logger(logger *logrus.Logger) {
 	m.logger = logger
@@ -74,7 +127,9 @@ func (m *Mapper) Name() string {
 }
 
 // HealthReport returns healthy status, or map of issues. Currently,
-// a closed database is reported as an error.
+// a closed database is reported as an error, and so are schema issues of
+// models registered with CheckSchema, keyed by "schema " and their tables
+// and columns.
 func (m *Mapper) HealthReport(ctx context.Context) (healthy bool, errors map[string]string) {
 	if m.Conn == nil {
 		return false, map[string]string{"error": "database is closed"}
@@ -83,5 +138,20 @@ func (m *Mapper) HealthReport(ctx context.Context) (healthy bool, errors map[str
 	if err != nil {
 		return false, map[string]string{"error": err.Error()}
 	}
-	return true, nil
+	models := m.schemaModels()
+	if len(models) == 0 {
+		return true, nil
+	}
+	diff, err := m.verifySchema(ctx, models...)
+	if err != nil {
+		return false, map[string]string{"schema": err.Error()}
+	}
+	if len(diff) == 0 {
+		return true, nil
+	}
+	errors = make(map[string]string, len(diff))
+	for _, issue := range diff {
+		errors["schema "+issue.Key()] = issue.String()
+	}
+	return false, errors
 }
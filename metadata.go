@@ -0,0 +1,249 @@
+package dmpr
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/pkg/errors"
+)
+
+// modelMetadata stores everything derived from a model's type only: its
+// field list, its relations, and templates of its queries. It is computed
+// once per type, and it is read-only afterwards.
+type modelMetadata struct {
+	// fields is the model's field list, with OptRelatedTo and OptUnrelated
+	// options set on fields of embedded structs
+	fields []FieldListItem
+	// relations maps relation names to their indexes in fields
+	relations map[string]int
+	// queryFields are the model's own columns (see FieldsFor)
+	queryFields []QueryField
+	// selected are the model's own columns, qualified with the "t1" alias
+	selected []string
+	hasID    bool
+	// insert and update are the parts of INSERT and UPDATE queries after
+	// the table name, for models without "omitempty" fields. They are
+	// empty for other models, whose queries depend on field values.
+	insert string
+	update string
+}
+
+// metadataCache stores metadata of model types
+type metadataCache struct {
+	sync.RWMutex
+	types map[reflect.Type]*modelMetadata
+}
+
+func (m *Mapper) metadataCache() *metadataCache {
+	if m.metadata == nil {
+		m.metadata = &metadataCache{}
+	}
+	return m.metadata
+}
+
+// modelMetadata returns the metadata of a model type, computing it on
+// first use. The type must be dereferenced.
+func (m *Mapper) modelMetadata(t reflect.Type) *modelMetadata {
+	cache := m.metadataCache()
+	cache.RLock()
+	meta, ok := cache.types[t]
+	cache.RUnlock()
+	if ok {
+		return meta
+	}
+	meta = newModelMetadata(t, m.Conn.Mapper.TypeMap(t).Index)
+	cache.Lock()
+	defer cache.Unlock()
+	if cached, ok := cache.types[t]; ok {
+		return cached
+	}
+	if cache.types == nil {
+		cache.types = map[reflect.Type]*modelMetadata{}
+	}
+	cache.types[t] = meta
+	return meta
+}
+
+// metadataOf returns the metadata of a model's type
+func (m *Mapper) metadataOf(model interface{}) (*modelMetadata, error) {
+	if err := m.tryOpen(); err != nil {
+		return nil, err
+	}
+	typ, _ := Reflect(model)
+	return m.modelMetadata(deref(typ)), nil
+}
+
+// newModelMetadata builds metadata from sqlx's field index of a type.
+// Options of the index are copied, as the index is shared by sqlx.
+func newModelMetadata(t reflect.Type, index []*reflectx.FieldInfo) *modelMetadata {
+	meta := &modelMetadata{
+		fields:    make([]FieldListItem, 0, len(index)),
+		relations: map[string]int{},
+	}
+	related := map[string]bool{}
+	for _, fi := range index {
+		if _, ok := fi.Options[OptBelongs]; ok {
+			related[fi.Path] = true
+		}
+	}
+	for _, fi := range index {
+		opts := make(map[string]string, len(fi.Options)+1)
+		for key, val := range fi.Options {
+			opts[key] = val
+		}
+		if fi.Parent.Field.Type != nil {
+			if related[fi.Parent.Path] {
+				opts[OptRelatedTo] = fi.Parent.Path
+			} else {
+				opts[OptUnrelated] = ""
+			}
+		}
+		fieldStruct := t.FieldByIndex(fi.Index)
+		item := FieldListItem{
+			Field:   fieldStruct,
+			Index:   fi.Index,
+			Name:    fi.Name,
+			Options: opts,
+			Path:    strings.ReplaceAll(fi.Path, ".", "_"),
+			Type:    fieldStruct.Type,
+		}
+		if _, ok := meta.relations[item.Path]; !ok && isRelation(item) {
+			meta.relations[item.Path] = len(meta.fields)
+		}
+		meta.fields = append(meta.fields, item)
+	}
+	meta.queryFields = ownFields(meta.fields)
+	omitempty := false
+	for _, field := range meta.queryFields {
+		if field.key == "id" {
+			meta.hasID = true
+		}
+		if _, ok := field.opts["omitempty"]; ok {
+			omitempty = true
+		}
+		meta.selected = append(meta.selected, "t1."+quoteIdentifier(field.key))
+	}
+	if !omitempty {
+		meta.insert, _ = meta.insertTemplate(nil)
+		meta.update, _ = meta.updateTemplate(nil)
+	}
+	return meta
+}
+
+// ownFields returns query fields of a field list, skipping relations,
+// read-only fields, and fields of embedded structs
+func ownFields(fields []FieldListItem) []QueryField {
+	queryFields := make([]QueryField, 0, len(fields))
+OwnFieldsLoop:
+	for _, fi := range fields {
+		for _, item := range []string{OptRelatedTo, OptUnrelated} {
+			if _, ok := fi.Options[item]; ok {
+				continue OwnFieldsLoop
+			}
+		}
+		field := fi.QField()
+		if field != nil {
+			queryFields = append(queryFields, *field)
+		}
+	}
+	return queryFields
+}
+
+// insertSQL returns the INSERT query of a model into a table. Empty
+// "omitempty" fields of the model are skipped, except "created_at", which
+// is set to NOW().
+func (meta *modelMetadata) insertSQL(table string, fieldmap func() map[string]reflect.Value) (string, error) {
+	suffix := meta.insert
+	if suffix == "" {
+		var err error
+		if suffix, err = meta.insertTemplate(fieldmap()); err != nil {
+			return "", err
+		}
+	}
+	return "INSERT INTO " + quoteIdentifier(table) + suffix, nil
+}
+
+// insertTemplate builds the part of an INSERT query after the table name.
+// Fieldmap is nil when building the template of a model without
+// "omitempty" fields.
+func (meta *modelMetadata) insertTemplate(fieldmap map[string]reflect.Value) (string, error) {
+	if len(meta.queryFields) < 1 {
+		return "", errors.New("nothing to create")
+	}
+	keys := make([]string, 0, len(meta.queryFields))
+	vals := make([]string, 0, len(meta.queryFields))
+	for _, field := range meta.queryFields {
+		if field.key == "id" {
+			continue
+		}
+		if _, ok := field.opts["omitempty"]; ok {
+			fieldVal, ok := fieldmap[field.key]
+			if !ok {
+				return "", errors.Errorf("unknown field key: %s", field.key)
+			}
+			if isEmptyValue(fieldVal) {
+				if field.key != "created_at" {
+					continue
+				}
+				field.val = "NOW()"
+			}
+		}
+		keys = append(keys, quoteIdentifier(field.key))
+		vals = append(vals, field.val)
+	}
+	return fmt.Sprintf(
+		" (%s) VALUES (%s)%s",
+		strings.Join(keys, ", "),
+		strings.Join(vals, ", "),
+		map[bool]string{true: " RETURNING id", false: ""}[meta.hasID],
+	), nil
+}
+
+// updateSQL returns the UPDATE query of a model in a table by its ID.
+// Empty "omitempty" fields of the model are skipped, except "updated_at",
+// which is set to NOW().
+func (meta *modelMetadata) updateSQL(table string, fieldmap func() map[string]reflect.Value) (string, error) {
+	suffix := meta.update
+	if suffix == "" {
+		var err error
+		if suffix, err = meta.updateTemplate(fieldmap()); err != nil {
+			return "", err
+		}
+	}
+	return "UPDATE " + quoteIdentifier(table) + suffix, nil
+}
+
+// updateTemplate builds the part of an UPDATE query after the table name.
+// Fieldmap is nil when building the template of a model without
+// "omitempty" fields.
+func (meta *modelMetadata) updateTemplate(fieldmap map[string]reflect.Value) (string, error) {
+	if !meta.hasID {
+		return "", errors.New("no ID field found")
+	}
+	keys := make([]string, 0, len(meta.queryFields))
+	for _, field := range meta.queryFields {
+		if field.key == "id" {
+			continue
+		}
+		if _, ok := field.opts["omitempty"]; ok {
+			fieldVal, ok := fieldmap[field.key]
+			if !ok {
+				return "", errors.Errorf("unknown field key: %s", field.key)
+			}
+			if isEmptyValue(fieldVal) {
+				if field.key != "updated_at" {
+					continue
+				}
+				field.eq = "updated_at=NOW()"
+			}
+		}
+		keys = append(keys, field.eq)
+	}
+	if len(keys) < 1 {
+		return "", errors.New("nothing to create")
+	}
+	return fmt.Sprintf(" SET %s WHERE id=:id RETURNING updated_at", strings.Join(keys, ", ")), nil
+}
@@ -0,0 +1,73 @@
+package dmpr
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMapper_modelMetadata(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	m := &Mapper{
+		Conn:     sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+		logger:   logrus.New(),
+		metadata: &metadataCache{},
+	}
+	typ := reflect.TypeOf(ExampleMember{})
+	var wg sync.WaitGroup
+	metas := make([]*modelMetadata, 10)
+	for idx := range metas {
+		wg.Add(1)
+		go func(idx int) {
+			defer wg.Done()
+			metas[idx] = m.FieldList(typ).meta
+		}(idx)
+	}
+	wg.Wait()
+	meta := m.modelMetadata(typ)
+	for _, item := range metas {
+		if item != meta {
+			t.Fatal("metadata is computed more than once")
+		}
+	}
+	for _, fi := range m.Conn.Mapper.TypeMap(typ).Index {
+		for _, opt := range []string{OptRelatedTo, OptUnrelated} {
+			if _, ok := fi.Options[opt]; ok {
+				t.Errorf("sqlx options of %s are modified: %v", fi.Path, fi.Options)
+			}
+		}
+	}
+	related := 0
+	for _, field := range meta.fields {
+		if field.Options[OptRelatedTo] == "account" {
+			related++
+		}
+	}
+	if related != 2 {
+		t.Errorf("related fields are not marked: %+v", meta.fields)
+	}
+	expected := map[string]string{
+		"insert": " (name, account_id) VALUES (:name, :account_id) RETURNING id",
+		"update": " SET name=:name, account_id=:account_id WHERE id=:id RETURNING updated_at",
+	}
+	received := map[string]string{"insert": meta.insert, "update": meta.update}
+	if !reflect.DeepEqual(expected, received) {
+		t.Errorf("templates don't match.\nExpected: %+v\nReceived: %+v", expected, received)
+	}
+	if relations := []string{"account", "posts"}; len(meta.relations) != len(relations) {
+		t.Errorf("relations don't match.\nExpected: %v\nReceived: %v", relations, meta.relations)
+	}
+	fl := m.FieldList(typ)
+	fl.Fields[0].Traversed = true
+	if meta.fields[0].Traversed {
+		t.Error("field list shares fields with the cache")
+	}
+}
@@ -0,0 +1,251 @@
+// Package migrate runs versioned SQL schema migrations through a dmpr.Mapper.
+//
+// Migrations are applied in the order of their versions, each one in its own
+// transaction, which also records the migration in a tracking table
+// ("schema_migrations" by default). Transactions take a PostgreSQL advisory
+// lock first, so concurrent runners (like multiple instances of the same
+// service starting at once) apply each migration only once.
+package migrate
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/julian7/dmpr"
+	"github.com/pkg/errors"
+)
+
+// DefaultTable is the default name of the table tracking applied migrations
+const DefaultTable = "schema_migrations"
+
+// Migration is a single schema change, with SQL statements applying (Up)
+// and reverting (Down) it
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+// String returns the migration's version and name
+func (m *Migration) String() string {
+	return fmt.Sprintf("%d_%s", m.Version, m.Name)
+}
+
+// MigrationStatus is a migration's state in the database. Migrations
+// applied to the database, but not known by the Migrator are reported as
+// Missing, with their version and name only.
+type MigrationStatus struct {
+	Migration *Migration
+	Applied   bool
+	AppliedAt time.Time
+	Missing   bool
+}
+
+// Migrator applies and reverts migrations on a database
+type Migrator struct {
+	mapper     *dmpr.Mapper
+	migrations []*Migration
+	table      string
+}
+
+// Option is an option of New
+type Option func(*Migrator)
+
+// Table sets the name of the table tracking applied migrations. It can be
+// qualified with a schema name.
+func Table(name string) Option {
+	return func(m *Migrator) {
+		m.table = name
+	}
+}
+
+// New returns a Migrator running migrations on mapper's database.
+// Migrations are sorted by their versions, which must be unique.
+func New(mapper *dmpr.Mapper, migrations []*Migration, opts ...Option) (*Migrator, error) {
+	m := &Migrator{
+		mapper:     mapper,
+		migrations: append([]*Migration{}, migrations...),
+		table:      DefaultTable,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+	for idx := 1; idx < len(m.migrations); idx++ {
+		if m.migrations[idx].Version == m.migrations[idx-1].Version {
+			return nil, errors.Errorf("duplicate migration version %d", m.migrations[idx].Version)
+		}
+	}
+	return m, nil
+}
+
+// Up applies all pending migrations in order. It stops at the first
+// failing migration, which is rolled back.
+func (m *Migrator) Up() error {
+	for _, migration := range m.migrations {
+		err := m.locked(func(tx *dmpr.Mapper) error {
+			applied, err := m.applied(tx, migration.Version)
+			if err != nil || applied {
+				return err
+			}
+			if _, err := tx.Exec(migration.Up); err != nil {
+				return err
+			}
+			_, err = tx.Exec(
+				fmt.Sprintf("INSERT INTO %s (version, name) VALUES ($1, $2)", m.quotedTable()),
+				migration.Version,
+				migration.Name,
+			)
+			return err
+		})
+		if err != nil {
+			return errors.Wrapf(err, "migrating up %s", migration)
+		}
+	}
+	return nil
+}
+
+// Down reverts the last n applied migrations, in reverse order. It stops
+// early if there are no more applied migrations.
+func (m *Migrator) Down(n int) error {
+	for ; n > 0; n-- {
+		done := false
+		var current *Migration
+		err := m.locked(func(tx *dmpr.Mapper) error {
+			var versions []int64
+			if err := tx.Select(&versions, fmt.Sprintf("SELECT version FROM %s ORDER BY version DESC LIMIT 1", m.quotedTable())); err != nil {
+				return err
+			}
+			if len(versions) < 1 {
+				done = true
+				return nil
+			}
+			current = m.migration(versions[0])
+			if current == nil {
+				return errors.Errorf("applied migration %d not found", versions[0])
+			}
+			if current.Down == "" {
+				return errors.Errorf("migration %s cannot be reverted", current)
+			}
+			if _, err := tx.Exec(current.Down); err != nil {
+				return err
+			}
+			_, err := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE version=$1", m.quotedTable()), current.Version)
+			return err
+		})
+		if err != nil {
+			if current != nil {
+				return errors.Wrapf(err, "migrating down %s", current)
+			}
+			return errors.Wrap(err, "migrating down")
+		}
+		if done {
+			break
+		}
+	}
+	return nil
+}
+
+// Status returns the state of all known migrations in order, followed by
+// migrations applied to the database, but not known by the Migrator. It
+// doesn't lock migrations, nor does it create the tracking table: if it
+// doesn't exist, no migrations are applied.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	var rows []struct {
+		Version   int64     `db:"version"`
+		Name      string    `db:"name"`
+		AppliedAt time.Time `db:"applied_at"`
+	}
+	var exists bool
+	if err := m.mapper.Get(&exists, "SELECT to_regclass($1) IS NOT NULL", m.quotedTable()); err != nil {
+		return nil, errors.Wrap(err, "migration status")
+	}
+	if exists {
+		err := m.mapper.Select(&rows, fmt.Sprintf("SELECT version, name, applied_at FROM %s ORDER BY version", m.quotedTable()))
+		if err != nil {
+			return nil, errors.Wrap(err, "migration status")
+		}
+	}
+	statuses := make([]MigrationStatus, 0, len(m.migrations))
+	known := map[int64]int{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = len(statuses)
+		statuses = append(statuses, MigrationStatus{Migration: migration})
+	}
+	for _, row := range rows {
+		if idx, ok := known[row.Version]; ok {
+			statuses[idx].Applied = true
+			statuses[idx].AppliedAt = row.AppliedAt
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{
+			Migration: &Migration{Version: row.Version, Name: row.Name},
+			Applied:   true,
+			AppliedAt: row.AppliedAt,
+			Missing:   true,
+		})
+	}
+	return statuses, nil
+}
+
+// locked runs fn in a transaction, holding the migrations' advisory lock,
+// and making sure the tracking table exists
+func (m *Migrator) locked(fn func(tx *dmpr.Mapper) error) error {
+	return m.mapper.Transaction(func(tx *dmpr.Mapper) error {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock($1)", m.lockID()); err != nil {
+			return errors.Wrap(err, "locking migrations")
+		}
+		_, err := tx.Exec(fmt.Sprintf(
+			"CREATE TABLE IF NOT EXISTS %s ("+
+				"version bigint PRIMARY KEY, "+
+				"name text NOT NULL, "+
+				"applied_at timestamptz NOT NULL DEFAULT now())",
+			m.quotedTable(),
+		))
+		if err != nil {
+			return errors.Wrap(err, "creating migrations table")
+		}
+		return fn(tx)
+	})
+}
+
+// applied checks whether a migration version is recorded as applied
+func (m *Migrator) applied(tx *dmpr.Mapper, version int64) (bool, error) {
+	var count int
+	err := tx.Get(&count, fmt.Sprintf("SELECT count(*) FROM %s WHERE version=$1", m.quotedTable()), version)
+	return count > 0, err
+}
+
+// migration returns a known migration by its version
+func (m *Migrator) migration(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// lockID returns the advisory lock's key, derived from the tracking table's
+// name, so migrators with different tables don't block each other
+func (m *Migrator) lockID() int64 {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte("dmpr/migrate:" + m.table))
+	return int64(hash.Sum64())
+}
+
+// quotedTable returns the tracking table's name quoted with the default
+// dialect
+func (m *Migrator) quotedTable() string {
+	parts := strings.Split(m.table, ".")
+	for idx, part := range parts {
+		parts[idx] = dmpr.DefaultDialect.QuoteIdentifier(part)
+	}
+	return strings.Join(parts, ".")
+}
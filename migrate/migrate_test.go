@@ -0,0 +1,172 @@
+package migrate
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/dmpr"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+)
+
+var testMigrations = []*Migration{
+	{Version: 2, Name: "add_email", Up: "ALTER TABLE users ADD email text", Down: "ALTER TABLE users DROP email"},
+	{Version: 1, Name: "create_users", Up: "CREATE TABLE users (id serial PRIMARY KEY)"},
+}
+
+func expectLocked(mock sqlmock.Sqlmock, m *Migrator) {
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta("SELECT pg_advisory_xact_lock($1)")).
+		WithArgs(m.lockID()).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("^CREATE TABLE IF NOT EXISTS schema_migrations \\(").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func expectTable(mock sqlmock.Sqlmock, exists bool) {
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT to_regclass($1) IS NOT NULL")).
+		WithArgs("schema_migrations").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+}
+
+func TestMigrator(t *testing.T) {
+	appliedAt := time.Date(2019, 11, 5, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		mocks    func(sqlmock.Sqlmock, *Migrator)
+		call     func(*Migrator) (interface{}, error)
+		expected interface{}
+		err      error
+	}{
+		{
+			name: "up applies pending migrations",
+			mocks: func(mock sqlmock.Sqlmock, m *Migrator) {
+				expectLocked(mock, m)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM schema_migrations WHERE version=$1")).
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+				mock.ExpectCommit()
+				expectLocked(mock, m)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM schema_migrations WHERE version=$1")).
+					WithArgs(2).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec("^ALTER TABLE users ADD email text$").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("INSERT INTO schema_migrations (version, name) VALUES ($1, $2)")).
+					WithArgs(2, "add_email").WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			},
+			call: func(m *Migrator) (interface{}, error) { return nil, m.Up() },
+		},
+		{
+			name: "up rolls back failing migration",
+			mocks: func(mock sqlmock.Sqlmock, m *Migrator) {
+				expectLocked(mock, m)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT count(*) FROM schema_migrations WHERE version=$1")).
+					WithArgs(1).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+				mock.ExpectExec("^CREATE TABLE users").WillReturnError(errors.New("syntax error"))
+				mock.ExpectRollback()
+			},
+			call: func(m *Migrator) (interface{}, error) { return nil, m.Up() },
+			err:  errors.New("migrating up 1_create_users: syntax error"),
+		},
+		{
+			name: "down reverts last migrations",
+			mocks: func(mock sqlmock.Sqlmock, m *Migrator) {
+				expectLocked(mock, m)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1")).
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+				mock.ExpectExec("^ALTER TABLE users DROP email$").WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectExec(regexp.QuoteMeta("DELETE FROM schema_migrations WHERE version=$1")).
+					WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+				expectLocked(mock, m)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1")).
+					WillReturnRows(sqlmock.NewRows([]string{"version"}))
+				mock.ExpectCommit()
+			},
+			call: func(m *Migrator) (interface{}, error) { return nil, m.Down(3) },
+		},
+		{
+			name: "down without down migration",
+			mocks: func(mock sqlmock.Sqlmock, m *Migrator) {
+				expectLocked(mock, m)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT version FROM schema_migrations ORDER BY version DESC LIMIT 1")).
+					WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+				mock.ExpectRollback()
+			},
+			call: func(m *Migrator) (interface{}, error) { return nil, m.Down(1) },
+			err:  errors.New("migrating down 1_create_users: migration 1_create_users cannot be reverted"),
+		},
+		{
+			name: "status",
+			mocks: func(mock sqlmock.Sqlmock, m *Migrator) {
+				expectTable(mock, true)
+				mock.ExpectQuery(regexp.QuoteMeta("SELECT version, name, applied_at FROM schema_migrations ORDER BY version")).
+					WillReturnRows(sqlmock.NewRows([]string{"version", "name", "applied_at"}).
+						AddRow(1, "create_users", appliedAt).
+						AddRow(3, "add_posts", appliedAt))
+			},
+			call: func(m *Migrator) (interface{}, error) { return m.Status() },
+			expected: []MigrationStatus{
+				{Migration: testMigrations[1], Applied: true, AppliedAt: appliedAt},
+				{Migration: testMigrations[0]},
+				{Migration: &Migration{Version: 3, Name: "add_posts"}, Applied: true, AppliedAt: appliedAt, Missing: true},
+			},
+		},
+		{
+			name: "status without tracking table",
+			mocks: func(mock sqlmock.Sqlmock, m *Migrator) {
+				expectTable(mock, false)
+			},
+			call: func(m *Migrator) (interface{}, error) { return m.Status() },
+			expected: []MigrationStatus{
+				{Migration: testMigrations[1]},
+				{Migration: testMigrations[0]},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			mapper := dmpr.New("")
+			mapper.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
+			migrator, err := New(mapper, testMigrations)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tt.mocks(mock, migrator)
+			result, err := tt.call(migrator)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if err == nil && tt.expected != nil && !reflect.DeepEqual(tt.expected, result) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(dmpr.New(""), []*Migration{{Version: 1, Name: "a"}, {Version: 1, Name: "b"}})
+	if assert := tester.AssertError(errors.New("duplicate migration version 1"), err); assert != nil {
+		t.Error(assert)
+	}
+}
+
+func TestMigrator_quotedTable(t *testing.T) {
+	migrator, err := New(dmpr.New(""), nil, Table("meta.Migrations"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected, actual := `meta."Migrations"`, migrator.quotedTable(); expected != actual {
+		t.Errorf("expected %s, received %s", expected, actual)
+	}
+}
@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    fstest.MapFS
+		expected []*Migration
+		err      error
+	}{
+		{
+			name: "up and down files",
+			files: fstest.MapFS{
+				"db/2_add_email.up.sql":      {Data: []byte("ALTER TABLE users ADD email text")},
+				"db/2_add_email.down.sql":    {Data: []byte("ALTER TABLE users DROP email")},
+				"db/10_create_posts.up.sql":  {Data: []byte("CREATE TABLE posts ()")},
+				"db/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users ()")},
+				"db/README.md":               {Data: []byte("migrations")},
+				"db/3_subdir.up.sql/file.go": {Data: []byte("package db")},
+			},
+			expected: []*Migration{
+				{Version: 1, Name: "create_users", Up: "CREATE TABLE users ()"},
+				{Version: 2, Name: "add_email", Up: "ALTER TABLE users ADD email text", Down: "ALTER TABLE users DROP email"},
+				{Version: 10, Name: "create_posts", Up: "CREATE TABLE posts ()"},
+			},
+		},
+		{
+			name: "down file only",
+			files: fstest.MapFS{
+				"db/1_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+			},
+			err: errors.New("migration 1_create_users has no up file"),
+		},
+		{
+			name: "conflicting names",
+			files: fstest.MapFS{
+				"db/1_create_users.up.sql":   {Data: []byte("CREATE TABLE users ()")},
+				"db/1_create_people.up.sql":  {Data: []byte("CREATE TABLE people ()")},
+				"db/1_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+			},
+			err: errors.New(`migration 1 has multiple names: "create_people" and "create_users"`),
+		},
+		{
+			name:  "missing directory",
+			files: fstest.MapFS{},
+			err:   errors.New("reading migrations: open db: file does not exist"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			migrations, err := Load(tt.files, "db")
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err == nil && !reflect.DeepEqual(tt.expected, migrations) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, migrations)
+			}
+		})
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"reflect"
 	"strings"
+	"sync/atomic"
+
+	"github.com/pkg/errors"
 )
 
 // Operator describes an operator, in which queries can build their WHERE clauses.
@@ -13,11 +16,33 @@ type Operator interface {
 	Values() map[string]interface{}
 }
 
+// Columner is an optional interface for operators, which reference columns.
+// Queries validate these columns against the model before running.
+type Columner interface {
+	Columns() []string
+}
+
+// Resolver is an optional interface for operators, which let queries
+// replace their columns with SQL expressions (like aggregates) when
+// rendering. If the callback returns an empty string, the column is
+// rendered as a quoted identifier.
+type Resolver interface {
+	Resolve(func(column string) string)
+}
+
+// Validator is an optional interface for operators, which can detect
+// errors at construction time. Queries call Validate before rendering
+// their WHERE clauses.
+type Validator interface {
+	Validate() error
+}
+
 // ColumnValue is a standard struct representing a database column and its desierd
 // value. This is the base struct of column-based operators.
 type ColumnValue struct {
 	column string
 	value  interface{}
+	expr   string
 }
 
 // Column returns returns the object's column name
@@ -25,6 +50,20 @@ func (c *ColumnValue) Column() string {
 	return c.column
 }
 
+// Columns returns the column the object references
+func (c *ColumnValue) Columns() []string {
+	return []string{c.column}
+}
+
+// Resolve replaces the column with an SQL expression in where clauses
+func (c *ColumnValue) Resolve(resolver func(string) string) {
+	c.expr = resolver(c.column)
+}
+
+func (c *ColumnValue) quotedColumn() string {
+	return resolvedColumn(c.column, c.expr)
+}
+
 // Keys returns keys the object returns, in order
 func (c *ColumnValue) Keys() []string {
 	return []string{c.column}
@@ -53,7 +92,7 @@ func (op *NULL) Values() map[string]interface{} {
 // Where returns NULL operator's where clause in positive (truthy) or
 // negative (falsy) manner.
 func (op *NULL) Where(truthy bool) string {
-	return op.column + " " + map[bool]string{true: "IS NULL", false: "IS NOT NULL"}[op.value == truthy]
+	return op.quotedColumn() + " " + map[bool]string{true: "IS NULL", false: "IS NOT NULL"}[op.value == truthy]
 }
 
 // BINARY implements a 2-parameter operator
@@ -79,7 +118,7 @@ func BinaryOp(col string, value interface{}, truthy, falsy string) Operator {
 func (op *BINARY) Where(truthy bool) string {
 	return fmt.Sprintf(
 		"%s %s :%s",
-		op.Column(),
+		op.quotedColumn(),
 		map[bool]string{true: op.TruthyRel, false: op.FalsyRel}[truthy],
 		op.Column(),
 	)
@@ -132,14 +171,14 @@ func (op *EQ) Where(truthy bool) string {
 		}
 		return fmt.Sprintf(
 			"%s %sIN (:%s)",
-			op.Column(),
+			op.quotedColumn(),
 			map[bool]string{true: "", false: "NOT "}[truthy],
 			op.Column(),
 		)
 	}
 	return fmt.Sprintf(
 		"%s %s :%s",
-		op.Column(),
+		op.quotedColumn(),
 		map[bool]string{true: "=", false: "<>"}[truthy],
 		op.Column(),
 	)
@@ -160,6 +199,21 @@ func (op *NOT) Where(truthy bool) string {
 	return op.Operator.Where(!truthy)
 }
 
+// Columns returns the columns of the negated operator
+func (op *NOT) Columns() []string {
+	return operatorColumns(op.Operator)
+}
+
+// Resolve resolves the columns of the negated operator
+func (op *NOT) Resolve(resolver func(string) string) {
+	resolveOperator(op.Operator, resolver)
+}
+
+// Validate validates the negated operator
+func (op *NOT) Validate() error {
+	return validateOperator(op.Operator)
+}
+
 // Grouper interface denotes a group operator, where multiple sub-operators
 // can be added into
 type Grouper interface {
@@ -202,6 +256,32 @@ func (op *GroupOperator) Values() map[string]interface{} {
 	return values
 }
 
+// Columns returns all the columns found in its sub-operators
+func (op *GroupOperator) Columns() []string {
+	columns := []string{}
+	for _, item := range op.items {
+		columns = append(columns, operatorColumns(item)...)
+	}
+	return columns
+}
+
+// Resolve resolves columns of all sub-operators
+func (op *GroupOperator) Resolve(resolver func(string) string) {
+	for _, item := range op.items {
+		resolveOperator(item, resolver)
+	}
+}
+
+// Validate validates all sub-operators, returning the first error found
+func (op *GroupOperator) Validate() error {
+	for _, item := range op.items {
+		if err := validateOperator(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Where is a helper function for implementer structs to provide all where clauses
 func (op *GroupOperator) Where(truthy bool) string {
 	if len(op.items) == 1 {
@@ -289,3 +369,228 @@ func (op *OR) Or(ops ...Operator) *OR {
 func (op *OR) Where(truthy bool) string {
 	return op.GroupOperator.Where(truthy)
 }
+
+// TEXTSEARCH implements PostgreSQL's full-text search operator. It is based
+// on Column struct.
+type TEXTSEARCH struct {
+	ColumnValue
+	config string
+}
+
+// TextSearch returns a full-text search operator, matching column's text
+// vector against a web search style query, like `to_tsvector(config, col)
+// @@ websearch_to_tsquery(config, :col)`. Config is the text search
+// configuration (eg. "english"); if it is empty, the database's default
+// configuration is used.
+func TextSearch(col, query, config string) *TEXTSEARCH {
+	return &TEXTSEARCH{ColumnValue: ColumnValue{column: col, value: query}, config: config}
+}
+
+// Where returns the full-text search match clause in positive or negated form
+func (op *TEXTSEARCH) Where(truthy bool) string {
+	ret := fmt.Sprintf("%s @@ %s", op.vector(), op.query())
+	if !truthy {
+		ret = fmt.Sprintf("NOT (%s)", ret)
+	}
+	return ret
+}
+
+// Rank returns a ts_rank expression of the same search, which can be used
+// for ordering results by relevance. It refers to the same named parameter
+// as Where.
+func (op *TEXTSEARCH) Rank() string {
+	return fmt.Sprintf("ts_rank(%s, %s)", op.vector(), op.query())
+}
+
+func (op *TEXTSEARCH) vector() string {
+	return fmt.Sprintf("to_tsvector(%s%s)", op.configArg(), op.quotedColumn())
+}
+
+func (op *TEXTSEARCH) query() string {
+	return fmt.Sprintf("websearch_to_tsquery(%s:%s)", op.configArg(), op.Column())
+}
+
+func (op *TEXTSEARCH) configArg() string {
+	if op.config == "" {
+		return ""
+	}
+	return quoteLiteral(op.config) + ", "
+}
+
+// RAW is an operator of a raw SQL fragment. Its positional parameters are
+// rebound into uniquely named parameters, so it can be mixed with other
+// operators.
+type RAW struct {
+	sql    string
+	keys   []string
+	values map[string]interface{}
+	err    error
+}
+
+var rawSequence uint64
+
+// Raw returns an operator of a raw SQL fragment, like `lower(email) = ?`.
+// Each `?` is a placeholder of the next argument; use `??` for a literal
+// question mark (eg. for jsonb operators). Question marks in quoted strings
+// and identifiers are left alone. The number of placeholders must match
+// the number of arguments, otherwise the query will fail validation.
+func Raw(sql string, args ...interface{}) *RAW {
+	op := &RAW{values: map[string]interface{}{}}
+	prefix := fmt.Sprintf("raw%d_", atomic.AddUint64(&rawSequence, 1))
+	var b strings.Builder
+	var quote rune
+	runes := []rune(sql)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '?' && i+1 < len(runes) && runes[i+1] == '?':
+			i++
+		case r == '?':
+			key := fmt.Sprintf("%s%d", prefix, len(op.keys)+1)
+			if len(op.keys) < len(args) {
+				op.values[key] = args[len(op.keys)]
+			}
+			op.keys = append(op.keys, key)
+			b.WriteString(":" + key)
+			continue
+		}
+		b.WriteRune(r)
+	}
+	op.sql = b.String()
+	if len(op.keys) != len(args) {
+		op.err = errors.Errorf("raw SQL %q has %d placeholders, but %d arguments", sql, len(op.keys), len(args))
+	}
+	return op
+}
+
+// Where returns the raw SQL fragment in parentheses, in positive or negated form
+func (op *RAW) Where(truthy bool) string {
+	if !truthy {
+		return fmt.Sprintf("NOT (%s)", op.sql)
+	}
+	return fmt.Sprintf("(%s)", op.sql)
+}
+
+// Keys returns the rebound parameter names in order
+func (op *RAW) Keys() []string {
+	return op.keys
+}
+
+// Values returns the rebound parameters' values
+func (op *RAW) Values() map[string]interface{} {
+	return op.values
+}
+
+// Validate reports placeholder and argument count mismatch
+func (op *RAW) Validate() error {
+	return op.err
+}
+
+// COLUMNS implements a 2-column comparison operator
+type COLUMNS struct {
+	left      string
+	right     string
+	leftExpr  string
+	rightExpr string
+	TruthyRel string
+	FalsyRel  string
+}
+
+// ColumnOp returns a 2-column operator, with a truthy or falsy operator
+// between the columns. Columns may be qualified with table aliases.
+//
+// Example: ColumnOp("updated_at", "created_at", ">", "<=") yields
+// `updated_at > created_at` in normal query, or `updated_at <= created_at`
+// in negated form.
+func ColumnOp(left, right, truthy, falsy string) *COLUMNS {
+	return &COLUMNS{left: left, right: right, TruthyRel: truthy, FalsyRel: falsy}
+}
+
+// Where implements column comparison's where clause
+func (op *COLUMNS) Where(truthy bool) string {
+	return fmt.Sprintf(
+		"%s %s %s",
+		resolvedColumn(op.left, op.leftExpr),
+		map[bool]string{true: op.TruthyRel, false: op.FalsyRel}[truthy],
+		resolvedColumn(op.right, op.rightExpr),
+	)
+}
+
+// Resolve replaces both columns with SQL expressions in where clauses
+func (op *COLUMNS) Resolve(resolver func(string) string) {
+	op.leftExpr = resolver(op.left)
+	op.rightExpr = resolver(op.right)
+}
+
+// Columns returns both compared columns
+func (op *COLUMNS) Columns() []string {
+	return []string{op.left, op.right}
+}
+
+// Keys returns no keys, as column comparison has no parameters
+func (op *COLUMNS) Keys() []string {
+	return []string{}
+}
+
+// Values returns no values, as column comparison has no parameters
+func (op *COLUMNS) Values() map[string]interface{} {
+	return map[string]interface{}{}
+}
+
+// ColEq returns a column = column operator
+func ColEq(left, right string) *COLUMNS {
+	return ColumnOp(left, right, "=", "<>")
+}
+
+// ColLt returns a column < column operator
+func ColLt(left, right string) *COLUMNS {
+	return ColumnOp(left, right, "<", ">=")
+}
+
+// ColGt returns a column > column operator
+func ColGt(left, right string) *COLUMNS {
+	return ColumnOp(left, right, ">", "<=")
+}
+
+// ColLe returns a column <= column operator
+func ColLe(left, right string) *COLUMNS {
+	return ColumnOp(left, right, "<=", ">")
+}
+
+// ColGe returns a column >= column operator
+func ColGe(left, right string) *COLUMNS {
+	return ColumnOp(left, right, ">=", "<")
+}
+
+func operatorColumns(op Operator) []string {
+	if columner, ok := op.(Columner); ok {
+		return columner.Columns()
+	}
+	return nil
+}
+
+func resolvedColumn(column, expr string) string {
+	if expr != "" {
+		return expr
+	}
+	return quoteIdentifier(column)
+}
+
+func resolveOperator(op Operator, resolver func(string) string) {
+	if res, ok := op.(Resolver); ok {
+		res.Resolve(resolver)
+	}
+}
+
+func validateOperator(op Operator) error {
+	if validator, ok := op.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package dmpr
 
 import (
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -350,3 +352,170 @@ func TestOR_Or(t *testing.T) {
 		})
 	}
 }
+
+func TestTEXTSEARCH_Where(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		truthy bool
+		want   string
+	}{
+		{
+			name:   "default config",
+			truthy: true,
+			want:   "to_tsvector(body) @@ websearch_to_tsquery(:body)",
+		},
+		{
+			name:   "with config",
+			config: "english",
+			truthy: true,
+			want:   "to_tsvector('english', body) @@ websearch_to_tsquery('english', :body)",
+		},
+		{
+			name:   "negated",
+			config: "english",
+			truthy: false,
+			want:   "NOT (to_tsvector('english', body) @@ websearch_to_tsquery('english', :body))",
+		},
+		{
+			name:   "quoted config",
+			config: "it's",
+			truthy: true,
+			want:   "to_tsvector('it''s', body) @@ websearch_to_tsquery('it''s', :body)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := TextSearch("body", "cat -dog", tt.config)
+			if got := op.Where(tt.truthy); got != tt.want {
+				t.Errorf("TEXTSEARCH.Where() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRaw(t *testing.T) {
+	tests := []struct {
+		name   string
+		sql    string
+		args   []interface{}
+		truthy bool
+		want   string
+		values []interface{}
+		err    bool
+	}{
+		{
+			name:   "no placeholders",
+			sql:    "updated_at > created_at",
+			truthy: true,
+			want:   "(updated_at > created_at)",
+		},
+		{
+			name:   "rebinding placeholders",
+			sql:    "lower(email) = ? OR name = ?",
+			args:   []interface{}{"a@b.c", "test"},
+			truthy: true,
+			want:   "(lower(email) = :%[1]s1 OR name = :%[1]s2)",
+			values: []interface{}{"a@b.c", "test"},
+		},
+		{
+			name:   "negated",
+			sql:    "lower(email) = ?",
+			args:   []interface{}{"a@b.c"},
+			truthy: false,
+			want:   "NOT (lower(email) = :%[1]s1)",
+			values: []interface{}{"a@b.c"},
+		},
+		{
+			name:   "escaped and quoted question marks",
+			sql:    `data ?? 'what?' AND "odd?" = ?`,
+			args:   []interface{}{1},
+			truthy: true,
+			want:   `(data ? 'what?' AND "odd?" = :%[1]s1)`,
+			values: []interface{}{1},
+		},
+		{
+			name:   "missing argument",
+			sql:    "a = ? AND b = ?",
+			args:   []interface{}{1},
+			truthy: true,
+			want:   "(a = :%[1]s1 AND b = :%[1]s2)",
+			values: []interface{}{1, nil},
+			err:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := Raw(tt.sql, tt.args...)
+			prefix := ""
+			if keys := op.Keys(); len(keys) > 0 {
+				prefix = strings.TrimSuffix(keys[0], "1")
+			}
+			want := tt.want
+			if prefix != "" {
+				want = fmt.Sprintf(tt.want, prefix)
+			}
+			if got := op.Where(tt.truthy); got != want {
+				t.Errorf("RAW.Where() = %v, want %v", got, want)
+			}
+			values := op.Values()
+			for idx, key := range op.Keys() {
+				if !reflect.DeepEqual(values[key], tt.values[idx]) {
+					t.Errorf("RAW.Values()[%q] = %v, want %v", key, values[key], tt.values[idx])
+				}
+			}
+			if err := op.Validate(); (err != nil) != tt.err {
+				t.Errorf("RAW.Validate() = %v, want error: %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRaw_UniqueKeys(t *testing.T) {
+	op := And(Raw("a = ?", 1), Raw("b = ?", 2))
+	keys := op.Keys()
+	if len(keys) != 2 || keys[0] == keys[1] {
+		t.Errorf("Raw keys are not unique: %v", keys)
+	}
+}
+
+func TestCOLUMNS_Where(t *testing.T) {
+	tests := []struct {
+		name   string
+		op     Operator
+		truthy bool
+		want   string
+	}{
+		{
+			name:   "equal",
+			op:     ColEq("t1.owner_id", "t2.id"),
+			truthy: true,
+			want:   "t1.owner_id = t2.id",
+		},
+		{
+			name:   "negated equal",
+			op:     ColEq("t1.owner_id", "t2.id"),
+			truthy: false,
+			want:   "t1.owner_id <> t2.id",
+		},
+		{
+			name:   "greater than",
+			op:     ColGt("updated_at", "created_at"),
+			truthy: true,
+			want:   "updated_at > created_at",
+		},
+		{
+			name:   "in group",
+			op:     And(ColGt("updated_at", "created_at"), Eq("id", 1)),
+			truthy: true,
+			want:   "updated_at > created_at AND id = :id",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.op.Where(tt.truthy); got != tt.want {
+				t.Errorf("COLUMNS.Where() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -2,97 +2,133 @@ package dmpr
 
 import (
 	"fmt"
-	"strings"
+	"reflect"
 
 	"github.com/pkg/errors"
 )
 
 // Find searches database for a row by ID
 func (m *Mapper) Find(model interface{}, id int64) error {
-	table, err := tableName(model)
+	table, err := m.tableName(model)
 	if err != nil {
 		return err
 	}
+	if accessor, ok := model.(Accessor); ok {
+		return m.getAccessor(accessor, accessorSelect(accessor, table)+" WHERE id = $1", id)
+	}
 	return m.Get(
 		model,
-		fmt.Sprintf("SELECT * FROM %s WHERE id = $1", table),
+		fmt.Sprintf("SELECT * FROM %s WHERE id = $1", quoteIdentifier(table)),
 		id,
 	)
 }
 
-// FindBy searches database for a row by a column match
+// FindBy searches database for a row by a column match. Column must be
+// a column of the model, otherwise it returns *UnknownColumnError.
 func (m *Mapper) FindBy(model interface{}, column string, needle string) error {
-	table, err := tableName(model)
+	table, err := m.tableName(model)
 	if err != nil {
 		return err
 	}
+	typ, _ := Reflect(model)
+	fl := m.FieldList(typ)
+	if fl == nil {
+		return errors.New("cannot get field list")
+	}
+	if err := fl.ValidateColumn(column); err != nil {
+		return err
+	}
+	if accessor, ok := model.(Accessor); ok {
+		return m.getAccessor(accessor, accessorSelect(accessor, table)+" WHERE "+quoteIdentifier(column)+" = $1", needle)
+	}
 	return m.Get(
 		model,
-		fmt.Sprintf("SELECT * FROM %s WHERE %s = $1", table, column),
+		fmt.Sprintf("SELECT * FROM %s WHERE %s = $1", quoteIdentifier(table), quoteIdentifier(column)),
 		needle,
 	)
 }
 
 // All returns all elements into an array of models
 func (m *Mapper) All(models interface{}) error {
-	table, err := tableName(models)
+	table, err := m.tableName(models)
 	if err != nil {
 		return err
 	}
+	if t, value := Reflect(models); value.Kind() == reflect.Slice && isAccessor(deref(t)) {
+		model := reflect.New(deref(t)).Interface().(Accessor)
+		return m.selectAccessors(value, deref(t), accessorSelect(model, table))
+	}
 	return m.Select(
 		models,
-		fmt.Sprintf("SELECT * FROM %s", table),
+		fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(table)),
 	)
 }
 
-// Create inserts an item into the database
-func (m *Mapper) Create(model interface{}) error {
-	tablename, err := tableName(model)
+// QueryModels runs an arbitrary SQL query, and fills dest with the results.
+// Dest is a pointer of a slice of models (or pointers of models). Columns
+// are mapped the same way as in SelectQuery.All: related models' columns
+// have to be aliased with the relation's name as prefix (like `post_title`
+// for the "post" relation's "title" column), and rows with the same "id"
+// are merged into one model, collecting their "has many" relations.
+func (m *Mapper) QueryModels(dest interface{}, query string, args ...interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr || value.IsNil() {
+		return errors.New("non-nil pointer is expected as QueryModels destination")
+	}
+	value = reflect.Indirect(value)
+	if value.Kind() != reflect.Slice {
+		return errors.New("pointer to slice is expected as QueryModels destination")
+	}
+	t := deref(value.Type().Elem())
+	if t.Kind() != reflect.Struct {
+		return ErrInvalidType
+	}
+	fl := m.FieldList(t)
+	if fl == nil {
+		return errors.New("cannot get field list")
+	}
+	fl.JoinAll(m.FieldList)
+	rows, err := m.Queryx(query, args...)
+	if err != nil {
+		return errors.Wrap(err, "QueryModels query")
+	}
+	defer rows.Close()
+	return errors.Wrap(scanModels(rows, fl, value), "QueryModels")
+}
+
+// Create inserts an item into the database. With WithAssociations option,
+// it saves the model's related models too, in a single transaction.
+func (m *Mapper) Create(model interface{}, opts ...SaveOption) error {
+	options := newSaveOptions(opts)
+	if len(options.associations) == 0 {
+		return m.insert(model)
+	}
+	return m.Transaction(func(tx *Mapper) error {
+		return tx.saveAssociations(model, options.associations, tx.insert)
+	})
+}
+
+func (m *Mapper) insert(model interface{}) error {
+	tablename, err := m.tableName(model)
 	if err != nil {
 		return err
 	}
-	typ, _ := Reflect(model)
-	fields, err := m.FieldList(typ).FieldsFor()
+	if accessor, ok := model.(Accessor); ok {
+		query, args := accessor.DmprInsertSQL(quoteIdentifier(tablename))
+		return m.saveAccessor(accessor, query, args)
+	}
+	meta, err := m.metadataOf(model)
 	if err != nil {
 		return err
 	}
-	if len(fields) < 1 {
-		return errors.New("nothing to create")
-	}
-	keys := make([]string, 0, len(fields))
-	vals := make([]string, 0, len(fields))
-	fieldmap := m.FieldMap(model)
-	hasID := false
-	for _, field := range fields {
-		fieldVal, ok := fieldmap[field.key]
-		if !ok {
-			return errors.Errorf("unknown field key: %s", field.key)
-		}
-		if field.key == "id" {
-			hasID = true
-			continue
-		}
-		if _, ok := field.opts["omitempty"]; ok && isEmptyValue(fieldVal) {
-			if field.key != "created_at" {
-				continue
-			}
-			field.val = "NOW()"
-		}
-		keys = append(keys, field.key)
-		vals = append(vals, field.val)
+	query, err := meta.insertSQL(tablename, func() map[string]reflect.Value { return m.FieldMap(model) })
+	if err != nil {
+		return err
 	}
-	rows, err := m.NamedQuery(
-		fmt.Sprintf(
-			"INSERT INTO %s (%s) VALUES (%s)%s",
-			tablename,
-			strings.Join(keys, ", "),
-			strings.Join(vals, ", "),
-			map[bool]string{true: " RETURNING id", false: ""}[hasID],
-		),
-		model,
-	)
+	rows, err := m.NamedQuery(query, model)
 	if err == nil {
-		if hasID {
+		defer rows.Close()
+		if meta.hasID {
 			rows.Next()
 			err = rows.StructScan(model)
 			if err != nil {
@@ -103,55 +139,41 @@ func (m *Mapper) Create(model interface{}) error {
 	return err
 }
 
-// Update inserts an item into the database
-func (m *Mapper) Update(model interface{}) error {
-	tablename, err := tableName(model)
-	if err != nil {
-		return err
+// Update updates an item in the database. With WithAssociations option, it
+// saves the model's related models too, in a single transaction.
+func (m *Mapper) Update(model interface{}, opts ...SaveOption) error {
+	options := newSaveOptions(opts)
+	if len(options.associations) == 0 {
+		return m.update(model)
 	}
-	typ, _ := Reflect(model)
-	fields, err := m.FieldList(typ).FieldsFor()
+	return m.Transaction(func(tx *Mapper) error {
+		return tx.saveAssociations(model, options.associations, tx.update)
+	})
+}
+
+func (m *Mapper) update(model interface{}) error {
+	tablename, err := m.tableName(model)
 	if err != nil {
 		return err
 	}
-	keys := make([]string, 0, len(fields))
-	fieldmap := m.FieldMap(model)
-	hasID := false
-	hasUpdatedAt := true
-	for _, field := range fields {
-		fieldVal, ok := fieldmap[field.key]
-		if !ok {
-			return errors.Errorf("unknown field key: %s", field.key)
-		}
-		if field.key == "id" {
-			hasID = true
-			continue
+	if accessor, ok := model.(Accessor); ok {
+		query, args := accessor.DmprUpdateSQL(quoteIdentifier(tablename))
+		if query == "" {
+			return errors.New("no ID field found, or nothing to update")
 		}
-		if _, ok := field.opts["omitempty"]; ok && isEmptyValue(fieldVal) {
-			if field.key != "updated_at" {
-				continue
-			}
-			hasUpdatedAt = true
-			field.eq = "updated_at=NOW()"
-		}
-		keys = append(keys, field.eq)
+		return m.saveAccessor(accessor, query, args)
 	}
-	if !hasID {
-		return errors.New("no ID field found")
+	meta, err := m.metadataOf(model)
+	if err != nil {
+		return err
 	}
-	if len(keys) < 1 {
-		return errors.New("nothing to create")
+	query, err := meta.updateSQL(tablename, func() map[string]reflect.Value { return m.FieldMap(model) })
+	if err != nil {
+		return err
 	}
-	rows, err := m.NamedQuery(
-		fmt.Sprintf(
-			"UPDATE %s SET %s WHERE id=:id%s",
-			tablename,
-			strings.Join(keys, ", "),
-			map[bool]string{true: " RETURNING updated_at", false: ""}[hasUpdatedAt],
-		),
-		model,
-	)
-	if err == nil && hasUpdatedAt {
+	rows, err := m.NamedQuery(query, model)
+	if err == nil {
+		defer rows.Close()
 		rows.Next()
 		err = rows.StructScan(model)
 	}
@@ -160,14 +182,14 @@ func (m *Mapper) Update(model interface{}) error {
 
 // Delete deletes a row
 func (m *Mapper) Delete(model interface{}, id int64) error {
-	tablename, err := tableName(model)
+	tablename, err := m.tableName(model)
 	if err != nil {
 		return err
 	}
 	_, err = m.Exec(
 		fmt.Sprintf(
 			"DELETE FROM %s WHERE id = $1",
-			tablename,
+			quoteIdentifier(tablename),
 		),
 		id,
 	)
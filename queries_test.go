@@ -86,6 +86,69 @@ func TestMapper_Find(t *testing.T) {
 	}
 }
 
+func TestMapper_FindBy(t *testing.T) {
+	tests := []struct {
+		name     string
+		mocks    []func(sqlmock.Sqlmock)
+		model    interface{}
+		column   string
+		needle   string
+		expected interface{}
+		err      error
+	}{
+		{
+			name:   "unknown column",
+			model:  &ExampleModel{},
+			column: "name = name OR 1",
+			needle: "test",
+			err:    errors.New(`unknown column "name = name OR 1" in model dmpr.ExampleModel`),
+		},
+		{
+			name: "normal query",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "name"}).
+						AddRow(5, "test")
+					mock.ExpectQuery("^SELECT \\* FROM example_models WHERE name = \\$1").WithArgs("test").WillReturnRows(rows)
+				},
+			},
+			model:    &ExampleModel{},
+			column:   "name",
+			needle:   "test",
+			expected: &ExampleModel{ID: 5, Name: "test"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			for _, item := range tt.mocks {
+				item(mock)
+			}
+			mapper := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			err = mapper.FindBy(tt.model, tt.column, tt.needle)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err != nil {
+				return
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.expected != nil && !reflect.DeepEqual(tt.model, tt.expected) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, tt.model)
+			}
+		})
+	}
+}
+
 func TestMapper_Create(t *testing.T) {
 	now := time.Now()
 	tests := []struct {
@@ -174,3 +237,258 @@ func TestMapper_Create(t *testing.T) {
 		})
 	}
 }
+
+type ExampleAuthor struct {
+	ID    int
+	Name  string
+	Posts []*ExamplePost `db:"posts,relation=author"`
+}
+
+type ExamplePost struct {
+	ID       int
+	Title    string
+	AuthorID int `db:"author_id"`
+}
+
+func TestMapper_QueryModels(t *testing.T) {
+	query := "SELECT a.id, a.name, p.id AS posts_id, p.title AS posts_title, p.author_id AS posts_author_id " +
+		"FROM authors a JOIN posts p ON a.id = p.author_id WHERE p.title LIKE $1"
+	tests := []struct {
+		name     string
+		mocks    []func(sqlmock.Sqlmock)
+		dest     interface{}
+		expected interface{}
+		err      error
+	}{
+		{
+			name: "not a slice",
+			dest: &ExampleAuthor{},
+			err:  errors.New("pointer to slice is expected as QueryModels destination"),
+		},
+		{
+			name: "merging has many relation",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "name", "posts_id", "posts_title", "posts_author_id"}).
+						AddRow(1, "author", 2, "first", 1).
+						AddRow(1, "author", 3, "second", 1).
+						AddRow(4, "other", 5, "third", 4)
+					mock.ExpectQuery("^SELECT a\\.id").WithArgs("%i%").WillReturnRows(rows)
+				},
+			},
+			dest: &[]ExampleAuthor{},
+			expected: &[]ExampleAuthor{
+				{ID: 1, Name: "author", Posts: []*ExamplePost{
+					{ID: 2, Title: "first", AuthorID: 1},
+					{ID: 3, Title: "second", AuthorID: 1},
+				}},
+				{ID: 4, Name: "other", Posts: []*ExamplePost{
+					{ID: 5, Title: "third", AuthorID: 4},
+				}},
+			},
+		},
+		{
+			name: "slice of pointers",
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					rows := sqlmock.NewRows([]string{"id", "name", "posts_id", "posts_title", "posts_author_id"}).
+						AddRow(1, "author", 2, "first", 1).
+						AddRow(1, "author", 3, "second", 1)
+					mock.ExpectQuery("^SELECT a\\.id").WithArgs("%i%").WillReturnRows(rows)
+				},
+			},
+			dest: &[]*ExampleAuthor{},
+			expected: &[]*ExampleAuthor{
+				{ID: 1, Name: "author", Posts: []*ExamplePost{
+					{ID: 2, Title: "first", AuthorID: 1},
+					{ID: 3, Title: "second", AuthorID: 1},
+				}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			for _, item := range tt.mocks {
+				item(mock)
+			}
+			mapper := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			err = mapper.QueryModels(tt.dest, query, "%i%")
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err != nil {
+				return
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.expected != nil && !reflect.DeepEqual(tt.dest, tt.expected) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, tt.dest)
+			}
+		})
+	}
+}
+
+type ExampleAccount struct {
+	ID   int
+	Name string
+}
+
+type ExampleMember struct {
+	ID        int
+	Name      string
+	AccountID null.Int        `db:"account_id"`
+	Account   *ExampleAccount `db:"account,belongs"`
+	Posts     []ExamplePost   `db:"posts,relation=author"`
+}
+
+type ExampleProfile struct {
+	ID      int
+	Bio     string
+	OwnerID int `db:"owner_id"`
+}
+
+type ExampleOwner struct {
+	ID      int
+	Name    string
+	Profile *ExampleProfile `db:"profile,relation=owner"`
+}
+
+func TestMapper_CreateWithAssociations(t *testing.T) {
+	tests := []struct {
+		name     string
+		mocks    []func(sqlmock.Sqlmock)
+		model    interface{}
+		assocs   []string
+		expected interface{}
+		err      error
+	}{
+		{
+			name:   "unknown relation",
+			model:  &ExampleMember{Name: "member"},
+			assocs: []string{"groups"},
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectRollback()
+				},
+			},
+			err: errors.New(`Relation "groups" not found`),
+		},
+		{
+			name: "belongs to and has many",
+			model: &ExampleMember{
+				Name:    "member",
+				Account: &ExampleAccount{Name: "account"},
+				Posts:   []ExamplePost{{Title: "first"}, {Title: "second"}},
+			},
+			assocs: []string{"account", "posts"},
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectQuery("^INSERT INTO example_accounts \\(name\\) VALUES \\(\\?\\) RETURNING id$").
+						WithArgs("account").
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+					mock.ExpectQuery("^INSERT INTO example_members \\(name, account_id\\) VALUES \\(\\?, \\?\\) RETURNING id$").
+						WithArgs("member", 7).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+					mock.ExpectQuery("^INSERT INTO example_posts \\(title, author_id\\) VALUES \\(\\?, \\?\\) RETURNING id$").
+						WithArgs("first", 3).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(10))
+					mock.ExpectQuery("^INSERT INTO example_posts \\(title, author_id\\) VALUES \\(\\?, \\?\\) RETURNING id$").
+						WithArgs("second", 3).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(11))
+					mock.ExpectCommit()
+				},
+			},
+			expected: &ExampleMember{
+				ID:        3,
+				Name:      "member",
+				AccountID: null.IntFrom(7),
+				Account:   &ExampleAccount{ID: 7, Name: "account"},
+				Posts: []ExamplePost{
+					{ID: 10, Title: "first", AuthorID: 3},
+					{ID: 11, Title: "second", AuthorID: 3},
+				},
+			},
+		},
+		{
+			name: "has one",
+			model: &ExampleOwner{
+				Name:    "owner",
+				Profile: &ExampleProfile{Bio: "bio"},
+			},
+			assocs: []string{"profile"},
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectQuery("^INSERT INTO example_owners \\(name\\) VALUES \\(\\?\\) RETURNING id$").
+						WithArgs("owner").
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+					mock.ExpectQuery("^INSERT INTO example_profiles \\(bio, owner_id\\) VALUES \\(\\?, \\?\\) RETURNING id$").
+						WithArgs("bio", 4).
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(12))
+					mock.ExpectCommit()
+				},
+			},
+			expected: &ExampleOwner{
+				ID:      4,
+				Name:    "owner",
+				Profile: &ExampleProfile{ID: 12, Bio: "bio", OwnerID: 4},
+			},
+		},
+		{
+			name: "rollback on failure",
+			model: &ExampleMember{
+				Name:  "member",
+				Posts: []ExamplePost{{Title: "first"}},
+			},
+			assocs: []string{"account", "posts"},
+			mocks: []func(sqlmock.Sqlmock){
+				func(mock sqlmock.Sqlmock) {
+					mock.ExpectBegin()
+					mock.ExpectQuery("^INSERT INTO example_members").
+						WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(3))
+					mock.ExpectQuery("^INSERT INTO example_posts").
+						WillReturnError(errors.New("constraint violation"))
+					mock.ExpectRollback()
+				},
+			},
+			err: errors.New(`saving "posts": constraint violation`),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			for _, item := range tt.mocks {
+				item(mock)
+			}
+			mapper := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			err = mapper.Create(tt.model, WithAssociations(tt.assocs...))
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if tt.expected != nil && !reflect.DeepEqual(tt.model, tt.expected) {
+				t.Errorf("results don't match. Expected: %+v\nReceived: %+v", tt.expected, tt.model)
+			}
+		})
+	}
+}
@@ -0,0 +1,318 @@
+package dmpr
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	recursiveTable = "dmpr_tree"
+	recursiveDepth = "dmpr_depth"
+)
+
+// recursion describes a recursive query over a self-referencing relation
+type recursion struct {
+	relation string
+	depth    int
+}
+
+// recursiveRelation is a self-referencing relation prepared for recursive
+// loading
+type recursiveRelation struct {
+	field FieldListItem
+	// reference is the column of the referencing model, which stores the
+	// referenced model's ID
+	reference string
+	// ancestors is true for "belongs to" relations, when parents are
+	// loaded; false for "has one" and "has many" relations, when children
+	// are loaded.
+	ancestors bool
+}
+
+// Recursive makes All load a self-referencing relation recursively, using a
+// WITH RECURSIVE query. Models matching the query are loaded with their
+// "has one" or "has many" relation's whole subtree, or with their "belongs
+// to" relation's whole ancestor chain, up to depth levels. Depth 0 means no
+// limit, which never returns if there are cycles in the data. Example:
+//
+// ```golang
+// type Category struct {
+//     ID       int
+//     ParentID null.Int    `db:"parent_id"`
+//     Parent   *Category   `db:"parent,belongs"`
+//     Children []*Category `db:"children,relation=parent"`
+// }
+// ```
+//
+// Here, Recursive("children", 0) loads all descendants, while
+// Recursive("parent", 0) loads all ancestors of the selected categories.
+// Recursive queries don't support Select, Join, GroupBy, and WithCount (or
+// other relation aggregates), and they can be executed by All only.
+func (q *SelectQuery) Recursive(relation string, depth int) *SelectQuery {
+	q.recursive = &recursion{relation: relation, depth: depth}
+	return q
+}
+
+// recursiveRelation checks whether the recursive relation is
+// self-referencing, and returns its description.
+func (q *SelectQuery) recursiveRelation(fl *FieldList) (*recursiveRelation, error) {
+	field, err := fl.relationField(q.recursive.relation)
+	if err != nil {
+		return nil, err
+	}
+	t := deref(field.Type)
+	if t.Kind() == reflect.Slice {
+		t = deref(t.Elem())
+	}
+	if t != fl.Type {
+		return nil, errors.Errorf("relation %q is not self-referencing", q.recursive.relation)
+	}
+	if _, ok := field.Options[OptPolymorphic]; ok {
+		return nil, errors.Errorf("polymorphic relation %q cannot be loaded recursively", q.recursive.relation)
+	}
+	if _, ok := field.Options[OptBelongs]; ok {
+		return &recursiveRelation{field: field, reference: field.Path + "_id", ancestors: true}, nil
+	}
+	if _, ok := field.Options[OptThrough]; ok {
+		return nil, errors.Errorf("many-to-many relation %q cannot be loaded recursively", q.recursive.relation)
+	}
+	return &recursiveRelation{field: field, reference: field.Options[OptRelation] + "_id"}, nil
+}
+
+func (q *SelectQuery) recursiveSelector(fl *FieldList, rel *recursiveRelation) (string, []interface{}, error) {
+	if len(q.sel) > 0 || len(q.incl) > 0 || len(q.group) > 0 || len(q.relAggs) > 0 {
+		return "", nil, errors.New("Recursive cannot be combined with Select, Join, GroupBy, or relation aggregates")
+	}
+	table, err := q.mapper.tableName(q.model)
+	if err != nil {
+		return "", nil, err
+	}
+	fields, err := fl.FieldsFor()
+	if err != nil {
+		return "", nil, err
+	}
+	var anchorCols, recurCols, outerCols []string
+	for _, item := range fields {
+		column := quoteIdentifier(item.key)
+		anchorCols = append(anchorCols, "t1."+column)
+		recurCols = append(recurCols, "t2."+column)
+		outerCols = append(outerCols, "t1."+column)
+	}
+	tree := quoteIdentifier(recursiveTable)
+	depth := quoteIdentifier(recursiveDepth)
+	anchorCols = append(anchorCols, "0 AS "+depth)
+	recurCols = append(recurCols, fmt.Sprintf("%s.%s+1", tree, depth))
+	outerCols = append(outerCols, "t1."+depth)
+
+	anchorQuery := *q
+	anchorQuery.order = nil
+	anchor, args, err := anchorQuery.render(fl, anchorCols, []string{quoteIdentifier(table) + " t1"}, nil, nil)
+	if err != nil {
+		return "", nil, err
+	}
+
+	cond := fmt.Sprintf("t2.%s=%s.id", quoteIdentifier(rel.reference), tree)
+	if rel.ancestors {
+		cond = fmt.Sprintf("t2.id=%s.%s", tree, quoteIdentifier(rel.reference))
+	}
+	limit := ""
+	if q.recursive.depth > 0 {
+		limit = fmt.Sprintf(" WHERE %s.%s < %d", tree, depth, q.recursive.depth)
+	}
+	order, orderArgs, err := q.orderBy(fl)
+	if err != nil {
+		return "", nil, err
+	}
+	order = append([]string{"t1." + depth}, order...)
+	args = append(args, orderArgs...)
+
+	return fmt.Sprintf(
+		"WITH RECURSIVE %s AS (%s UNION ALL SELECT %s FROM %s t2 JOIN %s ON (%s)%s) "+
+			"SELECT %s FROM %s t1 ORDER BY %s",
+		tree,
+		anchor,
+		strings.Join(recurCols, ", "),
+		quoteIdentifier(table),
+		tree,
+		cond,
+		limit,
+		strings.Join(outerCols, ", "),
+		tree,
+		strings.Join(order, ", "),
+	), args, nil
+}
+
+// columnIndex returns the field index of a column of the model's table,
+// without allocating related models, like sqlx's FieldMap does.
+func (fl *FieldList) columnIndex(column string) ([]int, bool) {
+	for _, fi := range fl.Fields {
+		if fi.Path == column && !fl.isRelated(fi) && !isRelation(fi) {
+			return fi.Index, true
+		}
+	}
+	return nil, false
+}
+
+// recursiveNode is a model loaded by a recursive query
+type recursiveNode struct {
+	value reflect.Value
+	depth int
+	id    string
+	ref   string
+}
+
+// allRecursive executes a recursive SELECT query, and builds the model
+// trees into value.
+func (q *SelectQuery) allRecursive(fl *FieldList, value reflect.Value) error {
+	rel, err := q.recursiveRelation(fl)
+	if err != nil {
+		return err
+	}
+	idIndex, ok := fl.columnIndex("id")
+	if !ok {
+		return errors.New("no ID field found")
+	}
+	refIndex, ok := fl.columnIndex(rel.reference)
+	if !ok {
+		return &UnknownColumnError{Model: fl.Type, Column: rel.reference}
+	}
+	query, args, err := q.recursiveSelector(fl, rel)
+	if err != nil {
+		return err
+	}
+	rows, err := q.mapper.Queryx(query, args...)
+	if err != nil {
+		return errors.Wrap(err, "Recursive query")
+	}
+	defer rows.Close()
+	models, depths, err := scanWithExtra(rows, fl, recursiveDepth)
+	if err != nil {
+		return errors.Wrap(err, "Recursive")
+	}
+	nodes := make([]*recursiveNode, 0, len(models))
+	maxDepth := 0
+	for idx, vp := range models {
+		depth, err := depthValue(depths[idx])
+		if err != nil {
+			return err
+		}
+		nodes = append(nodes, &recursiveNode{
+			value: vp,
+			depth: depth,
+			id:    keyValue(vp.Elem().FieldByIndex(idIndex)),
+			ref:   keyValue(vp.Elem().FieldByIndex(refIndex)),
+		})
+		if depth > maxDepth {
+			maxDepth = depth
+		}
+	}
+	return buildTrees(nodes, maxDepth, rel, value)
+}
+
+// buildTrees links nodes to each other, from the deepest level up, so
+// relations referenced by value are complete when they are copied. Then,
+// it appends the roots to value.
+func buildTrees(nodes []*recursiveNode, maxDepth int, rel *recursiveRelation, value reflect.Value) error {
+	levels := make([]map[string][]*recursiveNode, maxDepth+1)
+	for idx := range levels {
+		levels[idx] = map[string][]*recursiveNode{}
+	}
+	for _, node := range nodes {
+		key := node.id
+		if rel.ancestors {
+			key = node.ref
+		}
+		levels[node.depth][key] = append(levels[node.depth][key], node)
+	}
+	for depth := maxDepth; depth > 0; depth-- {
+		for _, node := range nodes {
+			if node.depth != depth {
+				continue
+			}
+			key := node.ref
+			if rel.ancestors {
+				key = node.id
+			}
+			for _, target := range levels[depth-1][key] {
+				if err := attachNode(target.value.Elem().FieldByIndex(rel.field.Index), node.value); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	isPtr := value.Type().Elem().Kind() == reflect.Ptr
+	for _, node := range nodes {
+		if node.depth != 0 {
+			continue
+		}
+		if isPtr {
+			value.Set(reflect.Append(value, node.value))
+		} else {
+			value.Set(reflect.Append(value, node.value.Elem()))
+		}
+	}
+	return nil
+}
+
+// attachNode sets a relation field to a node (a pointer of the model), or
+// appends the node to it, if it's a slice.
+func attachNode(field, node reflect.Value) error {
+	switch {
+	case field.Kind() == reflect.Slice && field.Type().Elem().Kind() == reflect.Ptr:
+		field.Set(reflect.Append(field, node))
+	case field.Kind() == reflect.Slice:
+		field.Set(reflect.Append(field, node.Elem()))
+	case field.Kind() == reflect.Ptr:
+		field.Set(node)
+	case field.Kind() == reflect.Struct:
+		field.Set(node.Elem())
+	default:
+		return errors.Errorf("cannot attach %s to %s", node.Type(), field.Type())
+	}
+	return nil
+}
+
+// depthValue converts a scanned depth column to int
+func depthValue(scanned interface{}) (int, error) {
+	value := reflect.ValueOf(scanned)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(value.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(value.Uint()), nil
+	}
+	return 0, errors.Errorf("invalid %s value: %v", recursiveDepth, scanned)
+}
+
+// keyValue converts an ID or reference value to a comparable key. Null
+// values (like null.Int) are converted to an empty string.
+func keyValue(value reflect.Value) string {
+	v := keyArg(value)
+	if v == nil {
+		return ""
+	}
+	if b, ok := v.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+// keyArg returns an ID or reference value as a query argument. Null values
+// (like null.Int) are returned as nil.
+func keyArg(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+	v := value.Interface()
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return nil
+		}
+	}
+	return v
+}
@@ -4,7 +4,6 @@ import (
 	"database/sql/driver"
 	"reflect"
 
-	"github.com/gobuffalo/flect"
 	"github.com/pkg/errors"
 )
 
@@ -40,34 +39,6 @@ func deref(t reflect.Type) reflect.Type {
 	return t
 }
 
-func tableName(model interface{}) (string, error) {
-	t := reflect.TypeOf(model)
-	for {
-		if t == nil {
-			break
-		}
-		k := t.Kind()
-		if k == reflect.Slice || k == reflect.Array || k == reflect.Ptr {
-			t = t.Elem()
-		} else {
-			break
-		}
-	}
-	if t == nil {
-		return "", ErrInvalidType
-	}
-	return tableNameByType(t)
-}
-
-func tableNameByType(t reflect.Type) (string, error) {
-	t = deref(t)
-	modelName := flect.Underscore(t.Name())
-	if modelName == "" {
-		return "", ErrInvalidType
-	}
-	return flect.Pluralize(modelName), nil
-}
-
 // fieldByIndexes dials in to a value by index #s, returning the value inside. It allocates pointers and maps when needed.
 func fieldByIndexes(v reflect.Value, indexes []int) reflect.Value {
 	for _, i := range indexes {
@@ -98,9 +69,8 @@ func dialSubindex(v reflect.Value) reflect.Value {
 			v.Set(reflect.Append(v, newVal))
 			v = reflect.Indirect(newVal)
 		} else {
-			newVal := reflect.New(t)
-			v.Set(reflect.Append(v, newVal.Elem()))
-			v = newVal
+			v.Set(reflect.Append(v, reflect.Zero(t)))
+			v = v.Index(v.Len() - 1)
 		}
 	}
 	return v
@@ -127,6 +97,12 @@ func mergeFields(dst, src reflect.Value) (reflect.Value, error) {
 	return dst, nil
 }
 
+// IsEmpty checks whether a value is empty, as an "omitempty" field would be
+// omitted from INSERT and UPDATE queries. It is used by generated accessors.
+func IsEmpty(v interface{}) bool {
+	return isEmptyValue(reflect.ValueOf(v))
+}
+
 // copied from stdlib's encoding/json/encode.go, added driver.Valuer handling
 func isEmptyValue(v reflect.Value) bool {
 	switch v.Kind() {
@@ -0,0 +1,121 @@
+package dmpr
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// relationAggregate is an aggregate of a relation's models, selected with
+// each model by a correlated subquery
+type relationAggregate struct {
+	fn       string
+	relation string
+	column   string
+}
+
+// alias returns the name the aggregate is selected as
+func (a relationAggregate) alias() string {
+	if a.column == "*" {
+		return a.relation + "_" + a.fn
+	}
+	return a.relation + "_" + a.column + "_" + a.fn
+}
+
+// WithCount selects the number of related models of a "has one", "has many",
+// or "many-to-many" relation with each model, without joining them. The
+// count is selected as relation + "_count", which can be scanned into a
+// read-only field (see OptReadOnly), and used in OrderBy. Example:
+//
+// ```golang
+// type ToDoList struct {
+//     ID         int
+//     ToDoItems  []*ToDoItem `db:"to_do_items,relation=list"`
+//     ItemsCount int         `db:"to_do_items_count,readonly"`
+// }
+// ```
+//
+// ```golang
+// err := query.WithCount("to_do_items").OrderBy("to_do_items_count DESC").All()
+// ```
+func (q *SelectQuery) WithCount(relation string) *SelectQuery {
+	q.relAggs = append(q.relAggs, relationAggregate{fn: "count", relation: relation, column: "*"})
+	return q
+}
+
+// WithSum selects the sum of a column of related models with each model,
+// like WithCount. It is selected as relation + "_" + column + "_sum".
+func (q *SelectQuery) WithSum(relation, column string) *SelectQuery {
+	q.relAggs = append(q.relAggs, relationAggregate{fn: "sum", relation: relation, column: column})
+	return q
+}
+
+// WithMax selects the maximum of a column of related models with each
+// model, like WithCount. It is selected as relation + "_" + column + "_max".
+func (q *SelectQuery) WithMax(relation, column string) *SelectQuery {
+	q.relAggs = append(q.relAggs, relationAggregate{fn: "max", relation: relation, column: column})
+	return q
+}
+
+// relationAggregates returns the SQL expressions of relation aggregates by
+// their aliases
+func (q *SelectQuery) relationAggregates(fl *FieldList) (map[string]string, error) {
+	exprs := make(map[string]string, len(q.relAggs))
+	for _, agg := range q.relAggs {
+		expr, err := q.mapper.relationAggregate(fl, agg)
+		if err != nil {
+			return nil, err
+		}
+		exprs[agg.alias()] = expr
+	}
+	return exprs, nil
+}
+
+// relationAggregate renders a correlated subquery of an aggregate, which
+// references the model as "t1"
+func (m *Mapper) relationAggregate(fl *FieldList, agg relationAggregate) (string, error) {
+	plan, err := m.loadPlan(fl, agg.relation, "s1", "st")
+	if err != nil {
+		return "", err
+	}
+	if _, ok := plan.field.Options[OptBelongs]; ok {
+		return "", errors.Errorf("cannot aggregate belongs to relation %q", agg.relation)
+	}
+	table, err := m.tableNameByType(plan.target)
+	if err != nil {
+		return "", err
+	}
+	column := agg.column
+	if column != "*" {
+		target := m.FieldList(plan.target)
+		if target == nil {
+			return "", errors.New("cannot get field list")
+		}
+		if err := target.ValidateColumn(column); err != nil {
+			return "", err
+		}
+		column = plan.alias + "." + quoteIdentifier(column)
+	}
+	return fmt.Sprintf(
+		"(SELECT %s(%s) FROM %s %s%s WHERE %s=t1.%s%s)",
+		agg.fn,
+		column,
+		quoteIdentifier(table),
+		plan.alias,
+		plan.join,
+		plan.key,
+		quoteIdentifier(plan.ownerColumn),
+		plan.extra,
+	), nil
+}
+
+// isRelationAggregate checks whether column is an alias of a relation
+// aggregate
+func (q *SelectQuery) isRelationAggregate(column string) bool {
+	for _, agg := range q.relAggs {
+		if agg.alias() == column {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package dmpr
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaIssueKind is the kind of a difference between models and the
+// database schema
+type SchemaIssueKind string
+
+// Kinds of schema issues
+const (
+	SchemaMissingTable     SchemaIssueKind = "missing table"
+	SchemaMissingColumn    SchemaIssueKind = "missing column"
+	SchemaMissingLinkTable SchemaIssueKind = "missing link table"
+	SchemaNullability      SchemaIssueKind = "nullability mismatch"
+)
+
+// SchemaIssue is a difference between a model and its table in the
+// database
+type SchemaIssue struct {
+	Kind  SchemaIssueKind
+	Model reflect.Type
+	Table string
+	// Column is empty for missing tables
+	Column string
+	// Detail describes nullability mismatches
+	Detail string
+}
+
+// Key returns the issue's table and column, like "users.email"
+func (i SchemaIssue) Key() string {
+	if i.Column == "" {
+		return i.Table
+	}
+	return i.Table + "." + i.Column
+}
+
+// String returns a human readable description of the issue
+func (i SchemaIssue) String() string {
+	if i.Detail != "" {
+		return fmt.Sprintf("%s %s: %s", i.Kind, i.Key(), i.Detail)
+	}
+	return fmt.Sprintf("%s %s", i.Kind, i.Key())
+}
+
+// SchemaDiff is a list of differences between models and the database
+// schema
+type SchemaDiff []SchemaIssue
+
+// String returns all the issues, one per line
+func (d SchemaDiff) String() string {
+	lines := make([]string, 0, len(d))
+	for _, issue := range d {
+		lines = append(lines, issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
+
+// VerifySchema compares models with their tables in the database, using
+// information_schema.columns. It reports missing tables, missing columns,
+// missing linker tables of "many-to-many" relations, and nullability
+// mismatches: columns of nullable fields (pointers, sql.Null* and null.v3
+// types) should be nullable, while other columns should be NOT NULL. Read-
+// only fields and relations are not checked. An empty diff means models
+// and the database match.
+func (m *Mapper) VerifySchema(models ...interface{}) (SchemaDiff, error) {
+	return m.verifySchema(context.Background(), models...)
+}
+
+// verifySchema is VerifySchema running its queries with a context
+func (m *Mapper) verifySchema(ctx context.Context, models ...interface{}) (SchemaDiff, error) {
+	diff := SchemaDiff{}
+	for _, model := range models {
+		t, _ := Reflect(model)
+		if t == nil || t.Kind() != reflect.Struct {
+			return nil, ErrInvalidType
+		}
+		issues, err := m.verifyModel(ctx, t)
+		if err != nil {
+			return nil, errors.Wrapf(err, "verifying %s", t)
+		}
+		diff = append(diff, issues...)
+	}
+	return diff, nil
+}
+
+// verifyModel compares a model with its table, and its linker tables
+func (m *Mapper) verifyModel(ctx context.Context, t reflect.Type) (SchemaDiff, error) {
+	table, err := m.tableNameByType(t)
+	if err != nil {
+		return nil, err
+	}
+	fl := m.FieldList(t)
+	if fl == nil {
+		return nil, errors.New("cannot get field list")
+	}
+	columns, err := m.tableColumns(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+	var diff SchemaDiff
+	if columns == nil {
+		diff = append(diff, SchemaIssue{Kind: SchemaMissingTable, Model: t, Table: table})
+	} else {
+		fields, err := fl.FieldsFor()
+		if err != nil {
+			return nil, err
+		}
+		for _, field := range fields {
+			columnNullable, ok := columns[field.key]
+			if !ok {
+				diff = append(diff, SchemaIssue{Kind: SchemaMissingColumn, Model: t, Table: table, Column: field.key})
+				continue
+			}
+			index, _ := fl.columnIndex(field.key)
+			_, nullable, err := columnType(t.FieldByIndex(index).Type)
+			if err != nil || nullable == columnNullable {
+				continue
+			}
+			detail := "field is nullable, column is NOT NULL"
+			if columnNullable {
+				detail = "field is not nullable, column is nullable"
+			}
+			diff = append(diff, SchemaIssue{Kind: SchemaNullability, Model: t, Table: table, Column: field.key, Detail: detail})
+		}
+	}
+	for _, field := range fl.Fields {
+		relindex, hasRelIndex := field.Options[OptRelation]
+		revindex, hasRevIndex := field.Options[OptReverse]
+		through, hasThrough := field.Options[OptThrough]
+		if !hasRelIndex || !hasRevIndex || !hasThrough || len(field.Index) > 1 {
+			continue
+		}
+		linkColumns, err := m.tableColumns(ctx, through)
+		if err != nil {
+			return nil, err
+		}
+		if linkColumns == nil {
+			diff = append(diff, SchemaIssue{Kind: SchemaMissingLinkTable, Model: t, Table: through})
+			continue
+		}
+		for _, column := range []string{relindex + "_id", revindex + "_id"} {
+			if _, ok := linkColumns[column]; !ok {
+				diff = append(diff, SchemaIssue{Kind: SchemaMissingColumn, Model: t, Table: through, Column: column})
+			}
+		}
+	}
+	return diff, nil
+}
+
+// tableColumns returns columns of a table (which can be schema-qualified),
+// with their nullability. It returns nil if the table doesn't exist.
+func (m *Mapper) tableColumns(ctx context.Context, table string) (map[string]bool, error) {
+	schema := ""
+	if idx := strings.LastIndex(table, "."); idx >= 0 {
+		schema, table = table[:idx], table[idx+1:]
+	}
+	var rows []struct {
+		Name     string `db:"column_name"`
+		Nullable string `db:"is_nullable"`
+	}
+	err := m.selectContext(
+		ctx,
+		&rows,
+		"SELECT column_name, is_nullable FROM information_schema.columns "+
+			"WHERE table_schema = coalesce(nullif($1, ''), current_schema()) AND table_name = $2",
+		schema,
+		table,
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "querying columns")
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		columns[row.Name] = row.Nullable == "YES"
+	}
+	return columns, nil
+}
+
+// schemaChecks stores models registered with CheckSchema
+type schemaChecks struct {
+	sync.Mutex
+	models []interface{}
+}
+
+// CheckSchema makes HealthReport verify models with VerifySchema, reporting
+// schema issues as health issues.
+func (m *Mapper) CheckSchema(models ...interface{}) {
+	if m.checks == nil {
+		m.checks = &schemaChecks{}
+	}
+	m.checks.Lock()
+	defer m.checks.Unlock()
+	m.checks.models = append(m.checks.models, models...)
+}
+
+// schemaModels returns a copy of models registered with CheckSchema
+func (m *Mapper) schemaModels() []interface{} {
+	if m.checks == nil {
+		return nil
+	}
+	m.checks.Lock()
+	defer m.checks.Unlock()
+	return append([]interface{}(nil), m.checks.models...)
+}
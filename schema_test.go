@@ -0,0 +1,174 @@
+package dmpr
+
+import (
+	"context"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/julian7/tester"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+var columnsQuery = regexp.QuoteMeta("SELECT column_name, is_nullable FROM information_schema.columns " +
+	"WHERE table_schema = coalesce(nullif($1, ''), current_schema()) AND table_name = $2")
+
+func expectColumns(mock sqlmock.Sqlmock, schema, table string, columns ...string) {
+	rows := sqlmock.NewRows([]string{"column_name", "is_nullable"})
+	for idx := 0; idx+1 < len(columns); idx += 2 {
+		rows.AddRow(columns[idx], columns[idx+1])
+	}
+	mock.ExpectQuery(columnsQuery).WithArgs(schema, table).WillReturnRows(rows)
+}
+
+type ExampleSchemaLegacy struct {
+	ID int `db:"id"`
+}
+
+func (ExampleSchemaLegacy) TableName() string {
+	return "legacy.tbl_things"
+}
+
+func TestMapper_VerifySchema(t *testing.T) {
+	userType := reflect.TypeOf(ExampleSchemaUser{})
+	tests := []struct {
+		name     string
+		models   []interface{}
+		mocks    func(sqlmock.Sqlmock)
+		expected SchemaDiff
+		err      error
+	}{
+		{
+			name:   "matching schema",
+			models: []interface{}{&ExampleSchemaRole{}, &ExampleSchemaLegacy{}},
+			mocks: func(mock sqlmock.Sqlmock) {
+				expectColumns(mock, "", "example_schema_roles", "id", "NO", "name", "YES", "created_at", "NO")
+				expectColumns(mock, "legacy", "tbl_things", "id", "NO")
+			},
+			expected: SchemaDiff{},
+		},
+		{
+			name:   "differences",
+			models: []interface{}{&ExampleSchemaUser{}, &ExampleSchemaAccount{}},
+			mocks: func(mock sqlmock.Sqlmock) {
+				expectColumns(mock, "", "example_schema_users",
+					"id", "NO", "account_id", "NO", "score", "YES", "active", "NO")
+				expectColumns(mock, "", "user_roles", "user_id", "NO")
+				expectColumns(mock, "", "example_schema_accounts")
+			},
+			expected: SchemaDiff{
+				{
+					Kind:   SchemaNullability,
+					Model:  userType,
+					Table:  "example_schema_users",
+					Column: "account_id",
+					Detail: "field is nullable, column is NOT NULL",
+				},
+				{
+					Kind:   SchemaNullability,
+					Model:  userType,
+					Table:  "example_schema_users",
+					Column: "score",
+					Detail: "field is not nullable, column is nullable",
+				},
+				{Kind: SchemaMissingColumn, Model: userType, Table: "example_schema_users", Column: "born"},
+				{Kind: SchemaMissingColumn, Model: userType, Table: "user_roles", Column: "role_id"},
+				{Kind: SchemaMissingTable, Model: reflect.TypeOf(ExampleSchemaAccount{}), Table: "example_schema_accounts"},
+			},
+		},
+		{
+			name:   "missing link table",
+			models: []interface{}{&ExampleSchemaUser{}},
+			mocks: func(mock sqlmock.Sqlmock) {
+				expectColumns(mock, "", "example_schema_users",
+					"id", "NO", "account_id", "YES", "score", "NO", "active", "NO", "born", "NO")
+				expectColumns(mock, "", "user_roles")
+			},
+			expected: SchemaDiff{
+				{Kind: SchemaMissingLinkTable, Model: userType, Table: "user_roles"},
+			},
+		},
+		{
+			name:   "query error",
+			models: []interface{}{&ExampleSchemaRole{}},
+			mocks: func(mock sqlmock.Sqlmock) {
+				mock.ExpectQuery(columnsQuery).WillReturnError(errors.New("permission denied"))
+			},
+			err: errors.New("verifying dmpr.ExampleSchemaRole: querying columns: permission denied"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+			tt.mocks(mock)
+			m := &Mapper{
+				Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+				logger: logrus.New(),
+			}
+			diff, err := m.VerifySchema(tt.models...)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+			if err == nil && !reflect.DeepEqual(tt.expected, diff) {
+				t.Errorf("results don't match.\nExpected: %+v\nReceived: %+v", tt.expected, diff)
+			}
+		})
+	}
+}
+
+func TestMapper_HealthReport_schema(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	expectColumns(mock, "", "example_schema_roles", "id", "NO")
+	m := &Mapper{
+		Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+		logger: logrus.New(),
+	}
+	m.CheckSchema(&ExampleSchemaRole{})
+	healthy, issues := m.HealthReport(context.Background())
+	expected := map[string]string{
+		"schema example_schema_roles.name": "missing column example_schema_roles.name",
+	}
+	if healthy || !reflect.DeepEqual(expected, issues) {
+		t.Errorf("unexpected health report: %v, %+v", healthy, issues)
+	}
+}
+
+func TestMapper_HealthReport_schemaContext(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	mock.ExpectQuery(columnsQuery).
+		WithArgs("", "example_schema_roles").
+		WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"column_name", "is_nullable"}))
+	m := New("")
+	m.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
+	m.CheckSchema(&ExampleSchemaRole{})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	healthy, issues := m.HealthReport(ctx)
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("schema check ignores the context: it took %v", elapsed)
+	}
+	if healthy || issues["schema"] == "" {
+		t.Errorf("unexpected health report: %v, %+v", healthy, issues)
+	}
+}
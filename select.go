@@ -5,6 +5,7 @@ import (
 	"reflect"
 	"strings"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/pkg/errors"
 )
 
@@ -15,11 +16,20 @@ type SelectQuery struct {
 	sel    []string
 	incl   []string
 	where  Operator
+	group  []string
+	having Operator
+	order  []orderItem
+	inner  map[string]bool
+	on     map[string]Operator
+	limit  map[string]*joinOptions
+
+	relAggs   []relationAggregate
+	recursive *recursion
 }
 
 // NewSelect returns a new SelectQuery with the provided model attached
 func (m *Mapper) NewSelect(model interface{}) (*SelectQuery, error) {
-	_, err := tableName(model)
+	_, err := m.tableName(model)
 	if err != nil {
 		return nil, err
 	}
@@ -43,7 +53,9 @@ func (m *Mapper) NewSelect(model interface{}) (*SelectQuery, error) {
 }
 
 // Select sets columns to be selected into model. By default, all fields
-// in the model and its joined relations are populated.
+// in the model and its joined relations are populated. Columns are validated
+// against the model (or against joined relations, if they are qualified
+// with the relation's name or table alias) when the query is run.
 func (q *SelectQuery) Select(selectors ...string) *SelectQuery {
 	if len(q.sel) < 1 {
 		q.sel = make([]string, 0, len(selectors))
@@ -85,7 +97,51 @@ func (q *SelectQuery) Join(selectors ...string) *SelectQuery {
 	return q
 }
 
+// InnerJoin prepares query for joining tables like Join, but with INNER
+// JOINs, so only models having the relations are returned.
+func (q *SelectQuery) InnerJoin(selectors ...string) *SelectQuery {
+	if q.inner == nil {
+		q.inner = map[string]bool{}
+	}
+	for _, selector := range selectors {
+		q.include(selector)
+		q.inner[selector] = true
+	}
+	return q
+}
+
+// JoinWhere adds an extra condition to a joined relation's ON clause,
+// joining the relation if it's not joined yet. Unlike Where, it doesn't
+// filter out models, only the related models not matching the condition.
+// Operator's unqualified columns reference the relation's table. Calling
+// it multiple times for the same relation will yield an AND relationship
+// among operators.
+func (q *SelectQuery) JoinWhere(relation string, op Operator) *SelectQuery {
+	if q.on == nil {
+		q.on = map[string]Operator{}
+	}
+	q.include(relation)
+	if q.on[relation] != nil {
+		q.on[relation] = And(q.on[relation], op)
+	} else {
+		q.on[relation] = op
+	}
+	return q
+}
+
+// include joins a relation, if it's not joined yet
+func (q *SelectQuery) include(relation string) {
+	for _, incl := range q.incl {
+		if incl == relation {
+			return
+		}
+	}
+	q.incl = append(q.incl, relation)
+}
+
 // Where sets where clauses to the SELECT query, using Operator interface.
+// Operators' columns can be qualified with joined relations' names (like
+// "post.title"); unqualified columns reference the model's table.
 // Calling it multiple times will yield an AND relationship among operators.
 func (q *SelectQuery) Where(op Operator) *SelectQuery {
 	if q.where != nil {
@@ -96,12 +152,56 @@ func (q *SelectQuery) Where(op Operator) *SelectQuery {
 	return q
 }
 
+// orderItem is an item of ORDER BY clause: a column, or a full-text
+// search's rank
+type orderItem struct {
+	column string
+	rank   *TEXTSEARCH
+	desc   bool
+}
+
+// OrderBy sorts results by columns. Columns can be qualified with joined
+// relations' names (like "post.created_at"), and they can have an " ASC"
+// or " DESC" suffix. Calling it multiple times appends more columns.
+func (q *SelectQuery) OrderBy(columns ...string) *SelectQuery {
+	for _, column := range columns {
+		q.order = append(q.order, parseOrderItem(column))
+	}
+	return q
+}
+
+// parseOrderItem parses a column with an optional " ASC" or " DESC" suffix
+func parseOrderItem(column string) orderItem {
+	item := orderItem{column: strings.TrimSpace(column)}
+	if idx := strings.LastIndex(item.column, " "); idx >= 0 {
+		switch strings.ToUpper(item.column[idx+1:]) {
+		case "DESC":
+			item.desc = true
+			fallthrough
+		case "ASC":
+			item.column = strings.TrimSpace(item.column[:idx])
+		}
+	}
+	return item
+}
+
+// OrderByRank sorts results by relevance of a full-text search, most
+// relevant first. It is usually called with the same operator provided
+// to Where.
+func (q *SelectQuery) OrderByRank(op *TEXTSEARCH) *SelectQuery {
+	q.order = append(q.order, orderItem{rank: op, desc: true})
+	return q
+}
+
 // All executes SELECT query, returning all the items selected. This call
 // evaluates provided parameters, builds SQL query, and populates model
 // slice the SelectQuery is created with.
 func (q *SelectQuery) All() error {
 	t, value := Reflect(q.model)
 	fl := q.mapper.FieldList(t)
+	if q.recursive != nil {
+		return q.allRecursive(fl, value)
+	}
 
 	query, args, err := q.allSelector(fl)
 	if err != nil {
@@ -112,112 +212,405 @@ func (q *SelectQuery) All() error {
 		return errors.Wrap(err, "SelectAll query")
 	}
 	defer rows.Close()
-	columns, err := rows.Columns()
+	return errors.Wrap(scanModels(rows, fl, value), "SelectAll")
+}
+
+// scanModels scans all rows into a slice of models (or pointers of models),
+// mapping columns by traversals. Rows with the same "id" column are merged
+// into a single model, appending their "has many" relations.
+func scanModels(rows *sqlx.Rows, fl *FieldList, value reflect.Value) error {
+	isPtr := value.Type().Elem().Kind() == reflect.Ptr
+	scanner, err := newRowScanner(rows, fl)
 	if err != nil {
-		return errors.Wrap(err, "SelectAll columns")
+		return err
+	}
+	index := map[int]int{}
+	rowNum := 0
+	for rows.Next() {
+		vp, id, err := scanner.scan()
+		if err != nil {
+			return err
+		}
+		if id != nil {
+			if otherRow, ok := index[*id]; ok {
+				if _, err := mergeFields(value.Index(otherRow), vp); err != nil {
+					return errors.Wrap(err, "merging fields")
+				}
+				continue
+			}
+			index[*id] = rowNum
+		}
+		if isPtr {
+			value.Set(reflect.Append(value, vp))
+		} else {
+			value.Set(reflect.Append(value, vp.Elem()))
+		}
+		rowNum++
 	}
-	fields, err := fl.TraversalsByName(columns)
+	return rows.Err()
+}
+
+// rowScanner scans result rows into new model instances by traversals, or
+// by generated accessors, if the model implements Accessor, and all the
+// columns are the model's own columns
+type rowScanner struct {
+	rows    *sqlx.Rows
+	t       reflect.Type
+	fields  Traversals
+	targets []int
+	values  []interface{}
+	idIndex int
+}
+
+func newRowScanner(rows *sqlx.Rows, fl *FieldList) (*rowScanner, error) {
+	columns, err := rows.Columns()
 	if err != nil {
-		return errors.Wrap(err, "SelectAll traversal")
+		return nil, errors.Wrap(err, "columns")
+	}
+	scanner := &rowScanner{
+		rows:    rows,
+		t:       fl.Type,
+		values:  make([]interface{}, len(columns)),
+		idIndex: -1,
+	}
+	if isAccessor(fl.Type) {
+		scanner.targets, _ = accessorIndexes(reflect.New(fl.Type).Interface().(Accessor), columns)
+	}
+	if scanner.targets == nil {
+		if scanner.fields, err = fl.TraversalsByName(columns); err != nil {
+			return nil, errors.Wrap(err, "traversal")
+		}
 	}
-	indexindex := -1
 	for idx := range columns {
 		if columns[idx] == "id" {
-			indexindex = idx
+			scanner.idIndex = idx
 			break
 		}
 	}
+	return scanner, nil
+}
+
+// scan scans the current row into a new model instance, returning its
+// pointer, and its ID, if there is an integer "id" column. Related models
+// are allocated only if at least one of their columns is not NULL.
+func (s *rowScanner) scan() (reflect.Value, *int, error) {
+	vp := reflect.New(s.t)
+	fill := func() {}
+	if s.targets != nil {
+		targets := vp.Interface().(Accessor).DmprScanTargets()
+		for idx, targetIdx := range s.targets {
+			s.values[idx] = targets[targetIdx]
+		}
+	} else {
+		var err error
+		if fill, err = s.fields.mapRelated(vp, s.values); err != nil {
+			return vp, nil, errors.Wrap(err, "traversal mapping")
+		}
+	}
+	if err := s.rows.Scan(s.values...); err != nil {
+		return vp, nil, errors.Wrap(err, "scan")
+	}
+	fill()
+	if s.idIndex >= 0 {
+		id := reflect.Indirect(reflect.ValueOf(s.values[s.idIndex]))
+		switch id.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			value := int(id.Int())
+			return vp, &value, nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			value := int(id.Uint())
+			return vp, &value, nil
+		}
+	}
+	return vp, nil, nil
+}
+
+// scanWithExtra scans rows into new model instances (pointers), except for
+// the last column, which has to be named extra. It's returned for each model
+// separately.
+func scanWithExtra(rows *sqlx.Rows, fl *FieldList, extra string) ([]reflect.Value, []interface{}, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "columns")
+	}
+	if len(columns) == 0 || columns[len(columns)-1] != extra {
+		return nil, nil, errors.Errorf("last column is expected to be %q", extra)
+	}
+	traversals, err := fl.TraversalsByName(columns[:len(columns)-1])
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "traversal")
+	}
+	traversals = append(traversals, &Traversal{Name: extra})
 	values := make([]interface{}, len(columns))
-	index := map[int]int{}
-	rowNum := 0
+	var models []reflect.Value
+	var extras []interface{}
 	for rows.Next() {
-		vp := reflect.New(t)
-		v := reflect.Indirect(vp)
-
-		if err := fields.Map(v, values); err != nil {
-			return errors.Wrap(err, "SelectAll traversal mapping")
+		vp := reflect.New(fl.Type)
+		if err := traversals.Map(vp, values); err != nil {
+			return nil, nil, errors.Wrap(err, "traversal mapping")
 		}
 		if err := rows.Scan(values...); err != nil {
-			return errors.Wrap(err, "SelectAll scan")
-		}
-		if indexindex >= 0 {
-			thisid, ok := values[indexindex].(*int)
-			if ok {
-				if otherRow, ok := index[*thisid]; ok {
-					updatedRow, err := mergeFields(value.Index(otherRow), v)
-					if err != nil {
-						return errors.Wrap(err, "SelectAll merging fields")
-					}
-					value.Index(otherRow).Set(updatedRow)
-					continue
-				}
-				index[*thisid] = rowNum
-			}
+			return nil, nil, errors.Wrap(err, "scan")
 		}
-		value.Set(reflect.Append(value, v))
-		rowNum++
+		models = append(models, vp)
+		extras = append(extras, *(values[len(values)-1].(*interface{})))
 	}
-	return rows.Err()
+	return models, extras, rows.Err()
 }
 
 func (q *SelectQuery) allSelector(fl *FieldList) (string, []interface{}, error) {
-	table, err := tableName(q.model)
+	table, err := q.mapper.tableName(q.model)
+	if err != nil {
+		return "", nil, err
+	}
+	joined, joinSelected, args, err := q.joins(fl)
 	if err != nil {
 		return "", nil, err
 	}
+	joined = append([]string{quoteIdentifier(table) + " t1"}, joined...)
 	var selected []string
-	joined := []string{table + " t1"}
 	if len(q.sel) >= 1 {
-		selected = q.sel
-	} else {
-		fields, err := fl.FieldsFor()
+		selected, err = q.resolveColumns(fl, q.sel)
 		if err != nil {
 			return "", nil, err
 		}
-		for _, item := range fields {
-			selected = append(selected, "t1."+item.key)
-		}
+	} else {
+		selected = append(append(selected, fl.selected()...), joinSelected...)
+	}
+	exprs, err := q.relationAggregates(fl)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, agg := range q.relAggs {
+		selected = append(selected, fmt.Sprintf("%s AS %s", exprs[agg.alias()], quoteIdentifier(agg.alias())))
+	}
+	return q.render(fl, selected, joined, args, nil)
+}
 
-		if len(q.incl) > 0 {
-			j, s, err := handleJoins(fl, q.incl, func(ref, tableref string) ([]string, []string, error) {
-				return fl.RelatedFieldsFor(ref, tableref, func(t reflect.Type) *FieldList {
-					return q.mapper.FieldList(t)
-				})
-			})
-			if err != nil {
-				return "", nil, err
-			}
-			joined = append(joined, j...)
-			selected = append(selected, s...)
+// render builds the SQL query from its selected columns and joined tables
+// (with their arguments), adding WHERE, GROUP BY, HAVING, and ORDER BY
+// clauses. HAVING clause can reference aggregates by their aliases
+// (aggregates maps aliases to SQL expressions).
+func (q *SelectQuery) render(fl *FieldList, selected, joined []string, joinArgs []interface{}, aggregates map[string]string) (string, []interface{}, error) {
+	var clauses strings.Builder
+	args := append([]interface{}{}, joinArgs...)
+	if q.where != nil {
+		if err := q.resolveOperator(fl, q.where, nil); err != nil {
+			return "", nil, err
 		}
+		clauses.WriteString(" WHERE " + q.where.Where(true))
+		args = append(args, operatorArgs(q.where)...)
 	}
-	whereClause := ""
-	args := []interface{}{}
-	if q.where != nil {
-		whereClause = fmt.Sprintf(" WHERE %s", q.where.Where(true))
-		values := q.where.Values()
-		for _, val := range q.where.Keys() {
-			args = append(args, values[val])
+	if len(q.group) > 0 {
+		grouped, err := q.resolveColumns(fl, q.group)
+		if err != nil {
+			return "", nil, err
+		}
+		clauses.WriteString(" GROUP BY " + strings.Join(grouped, ", "))
+	}
+	if q.having != nil {
+		if err := q.resolveOperator(fl, q.having, aggregates); err != nil {
+			return "", nil, err
+		}
+		clauses.WriteString(" HAVING " + q.having.Where(true))
+		args = append(args, operatorArgs(q.having)...)
+	}
+	if len(q.order) > 0 {
+		order, orderArgs, err := q.orderBy(fl)
+		if err != nil {
+			return "", nil, err
 		}
+		clauses.WriteString(" ORDER BY " + strings.Join(order, ", "))
+		args = append(args, orderArgs...)
 	}
 	return fmt.Sprintf("SELECT %s "+
 		"FROM %s%s",
 		strings.Join(selected, ", "),
-		strings.Join(joined, " LEFT JOIN "),
-		whereClause,
+		strings.Join(joined, " "),
+		clauses.String(),
 	), args, nil
 }
 
-func handleJoins(fl *FieldList, joins []string, fielder func(string, string) ([]string, []string, error)) ([]string, []string, error) {
+// orderBy returns the items of ORDER BY clause, and their arguments
+func (q *SelectQuery) orderBy(fl *FieldList) ([]string, []interface{}, error) {
+	order := make([]string, 0, len(q.order))
+	args := []interface{}{}
+	for _, item := range q.order {
+		var expr string
+		if item.rank != nil {
+			if err := q.resolveOperator(fl, item.rank, nil); err != nil {
+				return nil, nil, err
+			}
+			expr = item.rank.Rank()
+			args = append(args, operatorArgs(item.rank)...)
+		} else if q.isRelationAggregate(item.column) {
+			expr = quoteIdentifier(item.column)
+		} else {
+			var err error
+			if expr, err = q.resolveColumn(fl, item.column); err != nil {
+				return nil, nil, err
+			}
+		}
+		if item.desc {
+			expr += " DESC"
+		}
+		order = append(order, expr)
+	}
+	return order, args, nil
+}
+
+func operatorArgs(op Operator) []interface{} {
+	args := []interface{}{}
+	values := op.Values()
+	for _, key := range op.Keys() {
+		args = append(args, values[key])
+	}
+	return args
+}
+
+// resolveColumn validates a column, and returns it as an SQL expression
+// qualified with its table alias. Columns can be qualified with joined
+// relations' names (like "post.title"), or with table aliases: "t1" is the
+// model itself, while "t2", "t3", etc. are the joined relations in order.
+// Unqualified columns are the model's columns.
+func (q *SelectQuery) resolveColumn(fl *FieldList, column string) (string, error) {
+	return q.resolveColumnIn(fl, column, "t1")
+}
+
+// resolveColumnIn resolves a column like resolveColumn, but unqualified
+// columns reference the table of alias.
+func (q *SelectQuery) resolveColumnIn(fl *FieldList, column, alias string) (string, error) {
+	if column == "*" {
+		return column, nil
+	}
+	name := column
+	if parts := strings.SplitN(column, ".", 2); len(parts) > 1 {
+		alias, name = parts[0], parts[1]
+		for idx, incl := range q.incl {
+			if incl == alias {
+				alias = fmt.Sprintf("t%d", idx+2)
+				break
+			}
+		}
+	}
+	target, err := q.aliasFieldList(fl, alias)
+	if err != nil {
+		return "", err
+	}
+	if target == nil {
+		return "", &UnknownColumnError{Model: fl.Type, Column: column}
+	}
+	if name == "*" {
+		return alias + ".*", nil
+	}
+	if err := target.ValidateColumn(name); err != nil {
+		return "", err
+	}
+	return alias + "." + quoteIdentifier(name), nil
+}
+
+// resolveColumns resolves columns with resolveColumn
+func (q *SelectQuery) resolveColumns(fl *FieldList, columns []string) ([]string, error) {
+	resolved := make([]string, 0, len(columns))
+	for _, column := range columns {
+		expr, err := q.resolveColumn(fl, column)
+		if err != nil {
+			return nil, err
+		}
+		resolved = append(resolved, expr)
+	}
+	return resolved, nil
+}
+
+// resolveOperator validates an operator, and resolves its columns with
+// resolveColumn. Columns found in exprs (like aggregate aliases) are
+// replaced with their expressions instead.
+func (q *SelectQuery) resolveOperator(fl *FieldList, op Operator, exprs map[string]string) error {
+	return q.resolveOperatorIn(fl, op, exprs, "t1")
+}
+
+// resolveOperatorIn resolves an operator like resolveOperator, but
+// unqualified columns reference the table of alias.
+func (q *SelectQuery) resolveOperatorIn(fl *FieldList, op Operator, exprs map[string]string, alias string) error {
+	if err := validateOperator(op); err != nil {
+		return err
+	}
+	resolved := map[string]string{}
+	for _, column := range operatorColumns(op) {
+		if expr, ok := exprs[column]; ok {
+			resolved[column] = expr
+			continue
+		}
+		expr, err := q.resolveColumnIn(fl, column, alias)
+		if err != nil {
+			return err
+		}
+		resolved[column] = expr
+	}
+	resolveOperator(op, func(column string) string {
+		return resolved[column]
+	})
+	return nil
+}
+
+// aliasFieldList returns the field list of the model behind a table alias,
+// or nil if the alias is unknown.
+func (q *SelectQuery) aliasFieldList(fl *FieldList, alias string) (*FieldList, error) {
+	if alias == "t1" {
+		return fl, nil
+	}
+	for idx, incl := range q.incl {
+		if alias != fmt.Sprintf("t%d", idx+2) {
+			continue
+		}
+		for _, field := range fl.Fields {
+			if field.Path == incl {
+				t := deref(field.Type)
+				if t.Kind() == reflect.Slice {
+					t = t.Elem()
+				}
+				return q.mapper.FieldList(t), nil
+			}
+		}
+		return nil, errors.Errorf("Relation %q not found", incl)
+	}
+	return nil, nil
+}
+
+// joins builds JOIN clauses of joined relations, returning them with the
+// relations' columns to be selected, and the arguments of their conditions.
+func (q *SelectQuery) joins(fl *FieldList) ([]string, []string, []interface{}, error) {
 	var joined, selected []string
-	for idx, incl := range joins {
+	args := []interface{}{}
+	for idx, incl := range q.incl {
 		tableref := fmt.Sprintf("t%d", idx+2)
-		joining, selecting, err := fielder(incl, tableref)
+		joining, selecting, err := fl.relatedJoins(incl, tableref, func(t reflect.Type) *FieldList {
+			return q.mapper.FieldList(t)
+		})
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, err
+		}
+		if op := q.on[incl]; op != nil && len(joining) > 0 {
+			if err := q.resolveOperatorIn(fl, op, nil, tableref); err != nil {
+				return nil, nil, nil, err
+			}
+			joining[len(joining)-1].on += " AND " + op.Where(true)
+			args = append(args, operatorArgs(op)...)
+		}
+		clauses := joinStrings(joining)
+		if opts := q.limit[incl]; opts != nil {
+			if clauses, err = q.lateral(fl, incl, tableref, joining, opts); err != nil {
+				return nil, nil, nil, err
+			}
+		}
+		keyword := "LEFT JOIN "
+		if q.inner[incl] {
+			keyword = "INNER JOIN "
+		}
+		for _, clause := range clauses {
+			joined = append(joined, keyword+clause)
 		}
-		joined = append(joined, joining...)
 		selected = append(selected, selecting...)
 	}
-	return joined, selected, nil
+	return joined, selected, args, nil
 }
@@ -35,17 +35,98 @@ type ExampleHasMany struct {
 	Belongs []*ExampleBelongsTo `db:"belongs,relation=many"`
 }
 
+type ExampleHasManyValues struct {
+	ID      int
+	Name    string
+	Belongs []ExampleBelongsTo `db:"belongs,relation=many"`
+}
+
+type ExampleToDoList struct {
+	ID         int
+	ToDoItems  []*ExampleToDoItem `db:"to_do_items,relation=list"`
+	ItemsCount int                `db:"to_do_items_count,readonly"`
+	LastItemID null.Int           `db:"to_do_items_id_max,readonly"`
+}
+
+type ExampleToDoItem struct {
+	ID     int
+	ListID int `db:"list_id"`
+}
+
 type ExampleManyToMany struct {
 	ID     int
 	Name   string
 	Others []*ExampleManyToManyOther `db:"others,relation=other,reverse=many,through=manytomany_others"`
 }
 
+type ExampleManyToManyValues struct {
+	ID     int
+	Name   string
+	Others []ExampleManyToManyOther `db:"others,relation=other,reverse=many,through=manytomany_others"`
+}
+
 type ExampleManyToManyOther struct {
 	ID   int
 	Name string
 }
 
+type ExampleReservedWords struct {
+	ID    int
+	Order int    `db:"order"`
+	User  string `db:"user"`
+}
+
+type ExampleImage struct {
+	ID       int
+	Title    string
+	Comments []*ExampleComment `db:"comments,polymorphic=commentable"`
+}
+
+type ExampleClip struct {
+	ID       int
+	Title    string
+	Comments []*ExampleComment `db:"comments,polymorphic=commentable,polytype=Clip"`
+}
+
+type ExampleComment struct {
+	ID              int
+	Body            string
+	CommentableID   int           `db:"commentable_id"`
+	CommentableType string        `db:"commentable_type"`
+	Image           *ExampleImage `db:"image,belongs,polymorphic=commentable"`
+	Clip            *ExampleClip  `db:"clip,belongs,polymorphic=commentable,polytype=Clip"`
+}
+
+type ExampleCategory struct {
+	ID       int
+	Name     string
+	ParentID null.Int           `db:"parent_id"`
+	Parent   *ExampleCategory   `db:"parent,belongs"`
+	Children []*ExampleCategory `db:"children,relation=parent"`
+}
+
+type ExampleDoctor struct {
+	ID           int
+	Name         string
+	Appointments []*ExampleAppointment `db:"appointments,relation=doctor"`
+	Patients     []*ExamplePatient     `db:"patients,through=appointments,via=patient"`
+	Visitors     []*ExamplePatient     `db:"visitors,through=appointments,via=patient,links"`
+	PatientsSum  int                   `db:"patients_id_sum,readonly"`
+}
+
+type ExampleAppointment struct {
+	ID        int
+	DoctorID  int             `db:"doctor_id"`
+	PatientID int             `db:"patient_id"`
+	Room      string          `db:"room"`
+	Patient   *ExamplePatient `db:"patient,belongs"`
+}
+
+type ExamplePatient struct {
+	ID   int
+	Name string
+}
+
 func TestSelectQuery_All(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -84,7 +165,7 @@ func TestSelectQuery_All(t *testing.T) {
 			mock: func(mock sqlmock.Sqlmock) {
 				rows := sqlmock.NewRows([]string{"id", "extras"}).
 					AddRow(1, "test")
-				mock.ExpectQuery(`^SELECT id, extras FROM example_belongs_toes t1`).WillReturnRows(rows)
+				mock.ExpectQuery(`^SELECT t1\.id, t1\.extras FROM example_belongs_toes t1`).WillReturnRows(rows)
 			},
 			expected: &[]ExampleBelongsTo{
 				{ID: 1, Extras: null.StringFrom("test")},
@@ -99,13 +180,85 @@ func TestSelectQuery_All(t *testing.T) {
 					AddRow(3, "test", nil, 0, 0)
 				mock.ExpectQuery(fmt.Sprintf("^%s", regexp.QuoteMeta(
 					`SELECT t1.id, t1.name, t1.extras, t1.one_id, t1.more_id `+
-						`FROM example_belongs_toes t1 WHERE id = :id AND extras IS NULL`,
+						`FROM example_belongs_toes t1 WHERE t1.id = :id AND t1.extras IS NULL`,
 				))).WillReturnRows(rows)
 			},
 			expected: &[]ExampleBelongsTo{
 				{ID: 3, Name: "test", Extras: null.String{}, OneID: 0, MoreID: 0},
 			},
 		},
+		{
+			name:  "full text search",
+			model: &[]ExampleBelongsTo{},
+			prep: func(s *dmpr.SelectQuery) {
+				search := dmpr.TextSearch("name", "test", "english")
+				s.Where(search).OrderByRank(search)
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "extras", "one_id", "more_id"}).
+					AddRow(3, "test", nil, 0, 0)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t1.extras, t1.one_id, t1.more_id `+
+						`FROM example_belongs_toes t1 `+
+						`WHERE to_tsvector('english', t1.name) @@ websearch_to_tsquery('english', :name) `+
+						`ORDER BY ts_rank(to_tsvector('english', t1.name), websearch_to_tsquery('english', :name)) DESC`,
+				))).WithArgs("test", "test").WillReturnRows(rows)
+			},
+			expected: &[]ExampleBelongsTo{
+				{ID: 3, Name: "test", Extras: null.String{}, OneID: 0, MoreID: 0},
+			},
+		},
+		{
+			name:  "raw and column operators",
+			model: &[]ExampleBelongsTo{},
+			prep: func(s *dmpr.SelectQuery) {
+				s.Where(dmpr.Or(dmpr.ColGt("one_id", "more_id"), dmpr.Raw("lower(name) = ?", "test")))
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "extras", "one_id", "more_id"}).
+					AddRow(3, "test", nil, 0, 0)
+				mock.ExpectQuery(`^SELECT t1\.id, t1\.name, t1\.extras, t1\.one_id, t1\.more_id FROM example_belongs_toes t1 ` +
+					`WHERE t1\.one_id > t1\.more_id OR \(lower\(name\) = :raw\d+_1\)$`).
+					WithArgs("test").WillReturnRows(rows)
+			},
+			expected: &[]ExampleBelongsTo{
+				{ID: 3, Name: "test", Extras: null.String{}, OneID: 0, MoreID: 0},
+			},
+		},
+		{
+			name:  "unknown selected column",
+			model: &[]ExampleBelongsTo{},
+			prep:  func(s *dmpr.SelectQuery) { s.Select("id", "name; DROP TABLE users") },
+			err:   errors.New(`unknown column "name; DROP TABLE users" in model dmpr_test.ExampleBelongsTo`),
+		},
+		{
+			name:  "unknown filter column",
+			model: &[]ExampleBelongsTo{},
+			prep:  func(s *dmpr.SelectQuery) { s.Where(dmpr.Not(dmpr.Eq("password", "x"))) },
+			err:   errors.New(`unknown column "password" in model dmpr_test.ExampleBelongsTo`),
+		},
+		{
+			name:  "unknown table alias",
+			model: &[]ExampleBelongsTo{},
+			prep:  func(s *dmpr.SelectQuery) { s.Where(dmpr.Eq("t2.name", "x")) },
+			err:   errors.New(`unknown column "t2.name" in model dmpr_test.ExampleBelongsTo`),
+		},
+		{
+			name:  "reserved words",
+			model: &[]ExampleReservedWords{},
+			prep:  func(s *dmpr.SelectQuery) { s.Where(dmpr.Eq("order", 1)) },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "order", "user"}).
+					AddRow(3, 1, "test")
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1."order", t1."user" `+
+						`FROM example_reserved_words t1 WHERE t1."order" = :order`,
+				))).WithArgs(1).WillReturnRows(rows)
+			},
+			expected: &[]ExampleReservedWords{
+				{ID: 3, Order: 1, User: "test"},
+			},
+		},
 		{
 			name:  "belongs to",
 			model: &[]ExampleBelongsTo{},
@@ -149,6 +302,81 @@ func TestSelectQuery_All(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "filter and order by relation columns",
+			model: &[]ExampleHasMany{},
+			prep: func(s *dmpr.SelectQuery) {
+				s.Join("belongs").
+					Where(dmpr.Eq("belongs.name", "subname")).
+					Where(dmpr.Gt("id", 0)).
+					OrderBy("belongs.more_id DESC", "name")
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "belongs_id", "belongs_name", "belongs_extras", "belongs_one_id", "belongs_more_id"}).
+					AddRow(1, "test", 2, "subname", nil, 0, 1)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t2.id AS belongs_id, t2.name AS belongs_name, `+
+						`t2.extras AS belongs_extras, t2.one_id AS belongs_one_id, t2.more_id AS belongs_more_id `+
+						`FROM example_has_manies t1 LEFT JOIN example_belongs_toes t2 ON (t1.id=t2.many_id) `+
+						`WHERE t2.name = :belongs.name AND t1.id > :id ORDER BY t2.more_id DESC, t1.name`,
+				))).WithArgs("subname", 0).WillReturnRows(rows)
+			},
+			expected: &[]ExampleHasMany{
+				{ID: 1, Name: "test", Belongs: []*ExampleBelongsTo{{ID: 2, Name: "subname", MoreID: 1}}},
+			},
+		},
+		{
+			name:  "unknown relation column",
+			model: &[]ExampleHasMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("belongs").OrderBy("belongs.title") },
+			err:   errors.New(`unknown column "title" in model dmpr_test.ExampleBelongsTo`),
+		},
+		{
+			name:  "inner join",
+			model: &[]ExampleBelongsTo{},
+			prep:  func(s *dmpr.SelectQuery) { s.InnerJoin("one") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "extras", "one_id", "more_id", "one_id", "one_name"}).
+					AddRow(1, "test", nil, 2, 0, 2, "subname")
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t1.extras, t1.one_id, t1.more_id, t2.id AS one_id, t2.name AS one_name `+
+						`FROM example_belongs_toes t1 INNER JOIN example_has_ones t2 ON (t1.one_id=t2.id)`))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleBelongsTo{
+				{ID: 1, Name: "test", OneID: 2, One: ExampleHasOne{ID: 2, Name: "subname"}},
+			},
+		},
+		{
+			name:  "conditional join",
+			model: &[]ExampleHasMany{},
+			prep: func(s *dmpr.SelectQuery) {
+				s.JoinWhere("belongs", dmpr.Eq("more_id", 1)).
+					JoinWhere("belongs", dmpr.Null("extras", true)).
+					Where(dmpr.Eq("name", "test"))
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "belongs_id", "belongs_name", "belongs_extras", "belongs_one_id", "belongs_more_id"}).
+					AddRow(1, "test", 2, "subname", nil, 0, 1).
+					AddRow(4, "test", nil, nil, nil, nil, nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t2.id AS belongs_id, t2.name AS belongs_name, `+
+						`t2.extras AS belongs_extras, t2.one_id AS belongs_one_id, t2.more_id AS belongs_more_id `+
+						`FROM example_has_manies t1 LEFT JOIN example_belongs_toes t2 `+
+						`ON (t1.id=t2.many_id AND t2.more_id = :more_id AND t2.extras IS NULL) `+
+						`WHERE t1.name = :name`,
+				))).WithArgs(1, nil, "test").WillReturnRows(rows)
+			},
+			expected: &[]ExampleHasMany{
+				{ID: 1, Name: "test", Belongs: []*ExampleBelongsTo{{ID: 2, Name: "subname", MoreID: 1}}},
+				{ID: 4, Name: "test"},
+			},
+		},
+		{
+			name:  "conditional join with unknown column",
+			model: &[]ExampleHasMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.JoinWhere("belongs", dmpr.Eq("title", "x")) },
+			err:   errors.New(`unknown column "title" in model dmpr_test.ExampleBelongsTo`),
+		},
 		{
 			name:  "has many",
 			model: &[]ExampleHasMany{},
@@ -184,6 +412,388 @@ func TestSelectQuery_All(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:  "has one without match",
+			model: &[]ExampleHasOne{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("belongs") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "belongs_id", "belongs_name", "belongs_extras", "belongs_one_id", "belongs_more_id"}).
+					AddRow(1, "test", nil, nil, nil, nil, nil)
+				mock.ExpectQuery(`^SELECT t1\.id, t1\.name, t2\.id AS belongs_id`).WillReturnRows(rows)
+			},
+			expected: &[]ExampleHasOne{
+				{ID: 1, Name: "test"},
+			},
+		},
+		{
+			name:  "has many values",
+			model: &[]ExampleHasManyValues{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("belongs") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "belongs_id", "belongs_name", "belongs_extras", "belongs_one_id", "belongs_more_id"}).
+					AddRow(1, "test", 2, "subname", nil, 0, 1).
+					AddRow(1, "test", 3, "subname2", "extra", 0, 1).
+					AddRow(4, "test2", nil, nil, nil, nil, nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t2.id AS belongs_id, t2.name AS belongs_name, `+
+						`t2.extras AS belongs_extras, t2.one_id AS belongs_one_id, t2.more_id AS belongs_more_id `+
+						`FROM example_has_many_values t1 LEFT JOIN example_belongs_toes t2 ON (t1.id=t2.many_id)`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleHasManyValues{
+				{
+					ID:   1,
+					Name: "test",
+					Belongs: []ExampleBelongsTo{
+						{ID: 2, Name: "subname", MoreID: 1},
+						{ID: 3, Name: "subname2", Extras: null.StringFrom("extra"), MoreID: 1},
+					},
+				},
+				{ID: 4, Name: "test2"},
+			},
+		},
+		{
+			name:  "many to many values",
+			model: &[]ExampleManyToManyValues{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("others") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "others_id", "others_name"}).
+					AddRow(1, "test", 2, "subname").
+					AddRow(1, "test", 3, "subname2").
+					AddRow(5, "test2", nil, nil)
+				mock.ExpectQuery(`^SELECT t1\.id, t1\.name, t2\.id AS others_id, t2\.name AS others_name FROM example_many_to_many_values t1`).
+					WillReturnRows(rows)
+			},
+			expected: &[]ExampleManyToManyValues{
+				{
+					ID:   1,
+					Name: "test",
+					Others: []ExampleManyToManyOther{
+						{ID: 2, Name: "subname"},
+						{ID: 3, Name: "subname2"},
+					},
+				},
+				{ID: 5, Name: "test2"},
+			},
+		},
+		{
+			name:  "has many with underscored name",
+			model: &[]ExampleToDoList{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("to_do_items") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "to_do_items_id", "to_do_items_list_id"}).
+					AddRow(1, 2, 1).
+					AddRow(1, 3, 1)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t2.id AS to_do_items_id, t2.list_id AS to_do_items_list_id `+
+						`FROM example_to_do_lists t1 LEFT JOIN example_to_do_items t2 ON (t1.id=t2.list_id)`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleToDoList{
+				{ID: 1, ToDoItems: []*ExampleToDoItem{{ID: 2, ListID: 1}, {ID: 3, ListID: 1}}},
+			},
+		},
+		{
+			name:  "polymorphic has many",
+			model: &[]ExampleImage{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("comments") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "title", "comments_id", "comments_body", "comments_commentable_id", "comments_commentable_type"}).
+					AddRow(1, "image", 2, "nice", 1, "example_images").
+					AddRow(1, "image", 3, "great", 1, "example_images")
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.title, t2.id AS comments_id, t2.body AS comments_body, `+
+						`t2.commentable_id AS comments_commentable_id, t2.commentable_type AS comments_commentable_type `+
+						`FROM example_images t1 LEFT JOIN example_comments t2 `+
+						`ON (t1.id=t2.commentable_id AND t2.commentable_type='example_images')`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleImage{
+				{ID: 1, Title: "image", Comments: []*ExampleComment{
+					{ID: 2, Body: "nice", CommentableID: 1, CommentableType: "example_images"},
+					{ID: 3, Body: "great", CommentableID: 1, CommentableType: "example_images"},
+				}},
+			},
+		},
+		{
+			name:  "polymorphic belongs to",
+			model: &[]ExampleComment{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("image", "clip") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "body", "commentable_id", "commentable_type", "image_id", "image_title", "clip_id", "clip_title"}).
+					AddRow(2, "nice", 1, "example_images", 1, "image", nil, nil).
+					AddRow(3, "great", 1, "Clip", nil, nil, 1, "clip")
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.body, t1.commentable_id, t1.commentable_type, `+
+						`t2.id AS image_id, t2.title AS image_title, t3.id AS clip_id, t3.title AS clip_title `+
+						`FROM example_comments t1 `+
+						`LEFT JOIN example_images t2 ON (t1.commentable_id=t2.id AND t1.commentable_type='example_images') `+
+						`LEFT JOIN example_clips t3 ON (t1.commentable_id=t3.id AND t1.commentable_type='Clip')`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleComment{
+				{ID: 2, Body: "nice", CommentableID: 1, CommentableType: "example_images", Image: &ExampleImage{ID: 1, Title: "image"}},
+				{ID: 3, Body: "great", CommentableID: 1, CommentableType: "Clip", Clip: &ExampleClip{ID: 1, Title: "clip"}},
+			},
+		},
+		{
+			name:  "self-referencing belongs to",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("parent") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "parent_id", "parent_id", "parent_name", "parent_parent_id"}).
+					AddRow(1, "root", nil, nil, nil, nil).
+					AddRow(2, "child", 1, 1, "root", nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t1.parent_id, `+
+						`t2.id AS parent_id, t2.name AS parent_name, t2.parent_id AS parent_parent_id `+
+						`FROM example_categories t1 LEFT JOIN example_categories t2 ON (t1.parent_id=t2.id)`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleCategory{
+				{ID: 1, Name: "root"},
+				{ID: 2, Name: "child", ParentID: null.IntFrom(1), Parent: &ExampleCategory{ID: 1, Name: "root"}},
+			},
+		},
+		{
+			name:  "self-referencing has many",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("children") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "parent_id", "children_id", "children_name", "children_parent_id"}).
+					AddRow(1, "root", nil, 2, "child", 1).
+					AddRow(2, "child", 1, nil, nil, nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t1.parent_id, `+
+						`t2.id AS children_id, t2.name AS children_name, t2.parent_id AS children_parent_id `+
+						`FROM example_categories t1 LEFT JOIN example_categories t2 ON (t1.id=t2.parent_id)`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleCategory{
+				{ID: 1, Name: "root", Children: []*ExampleCategory{
+					{ID: 2, Name: "child", ParentID: null.IntFrom(1)},
+				}},
+				{ID: 2, Name: "child", ParentID: null.IntFrom(1)},
+			},
+		},
+		{
+			name:  "recursive subtree",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.Where(dmpr.Eq("id", 1)).Recursive("children", 2) },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "parent_id", "dmpr_depth"}).
+					AddRow(1, "root", nil, 0).
+					AddRow(2, "first", 1, 1).
+					AddRow(3, "second", 1, 1).
+					AddRow(4, "grandchild", 2, 2)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`WITH RECURSIVE dmpr_tree AS (`+
+						`SELECT t1.id, t1.name, t1.parent_id, 0 AS dmpr_depth FROM example_categories t1 WHERE t1.id = :id `+
+						`UNION ALL SELECT t2.id, t2.name, t2.parent_id, dmpr_tree.dmpr_depth+1 `+
+						`FROM example_categories t2 JOIN dmpr_tree ON (t2.parent_id=dmpr_tree.id) `+
+						`WHERE dmpr_tree.dmpr_depth < 2) `+
+						`SELECT t1.id, t1.name, t1.parent_id, t1.dmpr_depth FROM dmpr_tree t1 ORDER BY t1.dmpr_depth`,
+				))).WithArgs(1).WillReturnRows(rows)
+			},
+			expected: &[]ExampleCategory{
+				{ID: 1, Name: "root", Children: []*ExampleCategory{
+					{ID: 2, Name: "first", ParentID: null.IntFrom(1), Children: []*ExampleCategory{
+						{ID: 4, Name: "grandchild", ParentID: null.IntFrom(2)},
+					}},
+					{ID: 3, Name: "second", ParentID: null.IntFrom(1)},
+				}},
+			},
+		},
+		{
+			name:  "recursive ancestors",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.Where(dmpr.Eq("id", 4)).Recursive("parent", 0) },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "parent_id", "dmpr_depth"}).
+					AddRow(4, "grandchild", 2, 0).
+					AddRow(2, "first", 1, 1).
+					AddRow(1, "root", nil, 2)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`WITH RECURSIVE dmpr_tree AS (`+
+						`SELECT t1.id, t1.name, t1.parent_id, 0 AS dmpr_depth FROM example_categories t1 WHERE t1.id = :id `+
+						`UNION ALL SELECT t2.id, t2.name, t2.parent_id, dmpr_tree.dmpr_depth+1 `+
+						`FROM example_categories t2 JOIN dmpr_tree ON (t2.id=dmpr_tree.parent_id)) `+
+						`SELECT t1.id, t1.name, t1.parent_id, t1.dmpr_depth FROM dmpr_tree t1 ORDER BY t1.dmpr_depth`,
+				))).WithArgs(4).WillReturnRows(rows)
+			},
+			expected: &[]ExampleCategory{
+				{ID: 4, Name: "grandchild", ParentID: null.IntFrom(2), Parent: &ExampleCategory{
+					ID: 2, Name: "first", ParentID: null.IntFrom(1), Parent: &ExampleCategory{
+						ID: 1, Name: "root",
+					},
+				}},
+			},
+		},
+		{
+			name:  "recursive relation not self-referencing",
+			model: &[]ExampleHasMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.Recursive("belongs", 0) },
+			err:   errors.New(`relation "belongs" is not self-referencing`),
+		},
+		{
+			name:  "recursive with join",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("parent").Recursive("children", 0) },
+			err:   errors.New("Recursive cannot be combined with Select, Join, GroupBy, or relation aggregates"),
+		},
+		{
+			name:  "relation count and max",
+			model: &[]ExampleToDoList{},
+			prep: func(s *dmpr.SelectQuery) {
+				s.WithCount("to_do_items").WithMax("to_do_items", "id").OrderBy("to_do_items_count DESC")
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "to_do_items_count", "to_do_items_id_max"}).
+					AddRow(1, 2, 3).
+					AddRow(4, 0, nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, `+
+						`(SELECT count(*) FROM example_to_do_items s1 WHERE s1.list_id=t1.id) AS to_do_items_count, `+
+						`(SELECT max(s1.id) FROM example_to_do_items s1 WHERE s1.list_id=t1.id) AS to_do_items_id_max `+
+						`FROM example_to_do_lists t1 ORDER BY to_do_items_count DESC`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleToDoList{
+				{ID: 1, ItemsCount: 2, LastItemID: null.IntFrom(3)},
+				{ID: 4},
+			},
+		},
+		{
+			name:  "relation sum through model",
+			model: &[]ExampleDoctor{},
+			prep:  func(s *dmpr.SelectQuery) { s.Select("id").WithSum("patients", "id") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "patients_id_sum"}).
+					AddRow(1, 5)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, (SELECT sum(s1.id) FROM example_patients s1 `+
+						`JOIN example_appointments st ON (s1.id=st.patient_id) WHERE st.doctor_id=t1.id) AS patients_id_sum `+
+						`FROM example_doctors t1`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleDoctor{{ID: 1, PatientsSum: 5}},
+		},
+		{
+			name:  "relation sum of unknown column",
+			model: &[]ExampleToDoList{},
+			prep:  func(s *dmpr.SelectQuery) { s.WithSum("to_do_items", "size") },
+			err:   errors.New(`unknown column "size" in model dmpr_test.ExampleToDoItem`),
+		},
+		{
+			name:  "relation count of belongs to",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.WithCount("parent") },
+			err:   errors.New(`cannot aggregate belongs to relation "parent"`),
+		},
+		{
+			name:  "limited join",
+			model: &[]ExampleToDoList{},
+			prep: func(s *dmpr.SelectQuery) {
+				s.JoinLimited("to_do_items", dmpr.OrderBy("id DESC"), dmpr.Limit(2))
+			},
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "to_do_items_id", "to_do_items_list_id"}).
+					AddRow(1, 5, 1).
+					AddRow(1, 4, 1).
+					AddRow(6, nil, nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t2.id AS to_do_items_id, t2.list_id AS to_do_items_list_id `+
+						`FROM example_to_do_lists t1 LEFT JOIN LATERAL (SELECT t2.* FROM example_to_do_items t2 `+
+						`WHERE t1.id=t2.list_id ORDER BY t2.id DESC LIMIT 2) t2 ON true`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleToDoList{
+				{ID: 1, ToDoItems: []*ExampleToDoItem{{ID: 5, ListID: 1}, {ID: 4, ListID: 1}}},
+				{ID: 6},
+			},
+		},
+		{
+			name:  "limited many to many join",
+			model: &[]ExampleManyToMany{},
+			prep:  func(s *dmpr.SelectQuery) { s.JoinLimited("others", dmpr.Limit(1)) },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "others_id", "others_name"}).
+					AddRow(1, "test", 2, "other")
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t2.id AS others_id, t2.name AS others_name `+
+						`FROM example_many_to_manies t1 LEFT JOIN LATERAL (SELECT t2.* FROM manytomany_others tt2 `+
+						`JOIN example_many_to_many_others t2 ON (t2.id=tt2.many_id) WHERE t1.id=tt2.other_id LIMIT 1) t2 ON true`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleManyToMany{
+				{ID: 1, Name: "test", Others: []*ExampleManyToManyOther{{ID: 2, Name: "other"}}},
+			},
+		},
+		{
+			name:  "limited join of belongs to",
+			model: &[]ExampleCategory{},
+			prep:  func(s *dmpr.SelectQuery) { s.JoinLimited("parent", dmpr.Limit(1)) },
+			err:   errors.New(`relation "parent" is not a "has many" relation`),
+		},
+		{
+			name:  "limited join ordered by unknown column",
+			model: &[]ExampleToDoList{},
+			prep:  func(s *dmpr.SelectQuery) { s.JoinLimited("to_do_items", dmpr.OrderBy("title")) },
+			err:   errors.New(`unknown column "title" in model dmpr_test.ExampleToDoItem`),
+		},
+		{
+			name:  "has many through model",
+			model: &[]ExampleDoctor{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("patients") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{"id", "name", "patients_id", "patients_name"}).
+					AddRow(1, "House", 2, "Alice").
+					AddRow(1, "House", 3, "Bob").
+					AddRow(4, "Wilson", nil, nil)
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t2.id AS patients_id, t2.name AS patients_name `+
+						`FROM example_doctors t1 `+
+						`LEFT JOIN example_appointments tt2 ON (t1.id=tt2.doctor_id) `+
+						`LEFT JOIN example_patients t2 ON (t2.id=tt2.patient_id)`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleDoctor{
+				{ID: 1, Name: "House", Patients: []*ExamplePatient{{ID: 2, Name: "Alice"}, {ID: 3, Name: "Bob"}}},
+				{ID: 4, Name: "Wilson"},
+			},
+		},
+		{
+			name:  "has many through model with links",
+			model: &[]ExampleDoctor{},
+			prep:  func(s *dmpr.SelectQuery) { s.Join("visitors") },
+			mock: func(mock sqlmock.Sqlmock) {
+				rows := sqlmock.NewRows([]string{
+					"id", "name", "visitors_id", "visitors_name",
+					"appointments_id", "appointments_doctor_id", "appointments_patient_id", "appointments_room",
+				}).
+					AddRow(1, "House", 2, "Alice", 5, 1, 2, "101").
+					AddRow(1, "House", 2, "Alice", 6, 1, 2, "102")
+				mock.ExpectQuery(fmt.Sprintf("^%s$", regexp.QuoteMeta(
+					`SELECT t1.id, t1.name, t2.id AS visitors_id, t2.name AS visitors_name, `+
+						`tt2.id AS appointments_id, tt2.doctor_id AS appointments_doctor_id, `+
+						`tt2.patient_id AS appointments_patient_id, tt2.room AS appointments_room `+
+						`FROM example_doctors t1 `+
+						`LEFT JOIN example_appointments tt2 ON (t1.id=tt2.doctor_id) `+
+						`LEFT JOIN example_patients t2 ON (t2.id=tt2.patient_id)`,
+				))).WillReturnRows(rows)
+			},
+			expected: &[]ExampleDoctor{
+				{
+					ID:   1,
+					Name: "House",
+					Appointments: []*ExampleAppointment{
+						{ID: 5, DoctorID: 1, PatientID: 2, Room: "101"},
+						{ID: 6, DoctorID: 1, PatientID: 2, Room: "102"},
+					},
+					Visitors: []*ExamplePatient{{ID: 2, Name: "Alice"}, {ID: 2, Name: "Alice"}},
+				},
+			},
+		},
 		{
 			name:  "many to many",
 			model: &[]ExampleManyToMany{},
@@ -227,10 +837,10 @@ func TestSelectQuery_All(t *testing.T) {
 			mapper := dmpr.New("")
 			mapper.Conn = sqlx.NewDb(db, "sqlmock") // "sqlmock" is a magic string @ sqlmock for driver name
 			q, err := mapper.NewSelect(tt.model)
-			if assert := tester.AssertError(tt.err, err); assert != nil {
-				t.Error(assert)
-			}
 			if err != nil {
+				if assert := tester.AssertError(tt.err, err); assert != nil {
+					t.Error(assert)
+				}
 				return
 			}
 
@@ -0,0 +1,198 @@
+package dmpr
+
+import (
+	"container/list"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StatementCacheStats are counters of a mapper's prepared statement cache
+type StatementCacheStats struct {
+	// Size is the number of cached statements, Capacity is the maximum
+	Size     int
+	Capacity int
+	// Hits and Misses count queries run with cached and newly prepared
+	// statements; Evictions count statements closed to make room
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+// HitRate returns the ratio of queries run with cached statements
+func (s StatementCacheStats) HitRate() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+// stmtCache is an LRU cache of prepared statements, keyed by their SQL.
+// In transactions, statements not cached yet run unprepared, counted as
+// misses only.
+type stmtCache struct {
+	sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	lru      *list.List
+	stats    StatementCacheStats
+}
+
+// cachedStmt is a prepared statement of the cache. Evicted statements are
+// closed when they are not in use anymore.
+type cachedStmt struct {
+	query   string
+	stmt    *sqlx.Stmt
+	users   int
+	evicted bool
+}
+
+func newStmtCache(capacity int) *stmtCache {
+	return &stmtCache{
+		capacity: capacity,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		stats:    StatementCacheStats{Capacity: capacity},
+	}
+}
+
+// StatementCache enables caching of up to size prepared statements, keyed
+// by their SQL. Queries run by Exec, NamedExec, NamedQuery, Get, Select,
+// and Queryx use cached statements, preparing them on first use, and
+// closing the least recently used ones if the cache is full. Queries of
+// transactions use cached statements, but they don't prepare new ones. A
+// size of 0 disables the cache. It should be called before running queries.
+func (m *Mapper) StatementCache(size int) {
+	if m.statements != nil {
+		m.statements.closeAll()
+	}
+	m.statements = nil
+	if size > 0 {
+		m.statements = newStmtCache(size)
+	}
+}
+
+// StatementCacheStats returns counters of the prepared statement cache.
+// They are all zero if the cache is disabled.
+func (m *Mapper) StatementCacheStats() StatementCacheStats {
+	if m.statements == nil {
+		return StatementCacheStats{}
+	}
+	m.statements.Lock()
+	defer m.statements.Unlock()
+	stats := m.statements.stats
+	stats.Size = m.statements.lru.Len()
+	return stats
+}
+
+// prepared returns a cached prepared statement of a query, bound to the
+// mapper's transaction, if any, and a function releasing it. It returns
+// a nil statement if the cache is disabled. Transactions don't prepare new
+// statements, as preparing them on the database could wait for the
+// connection the transaction holds; they use cached ones only.
+func (m *Mapper) prepared(query string) (*sqlx.Stmt, func(), error) {
+	if m.statements == nil {
+		return nil, nil, nil
+	}
+	db := m.Conn
+	if m.tx != nil {
+		db = nil
+	}
+	entry, err := m.statements.acquire(db, query)
+	if err != nil || entry == nil {
+		return nil, nil, err
+	}
+	if m.tx != nil {
+		txStmt := m.tx.Stmtx(entry.stmt)
+		return txStmt, func() {
+			_ = txStmt.Close()
+			m.statements.release(entry)
+		}, nil
+	}
+	return entry.stmt, func() { m.statements.release(entry) }, nil
+}
+
+// acquire returns the cached statement of a query, preparing it on the
+// database if it's not cached yet. It returns nil if the statement is not
+// cached, and db is nil.
+func (c *stmtCache) acquire(db *sqlx.DB, query string) (*cachedStmt, error) {
+	c.Lock()
+	if elem, ok := c.entries[query]; ok {
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*cachedStmt)
+		entry.users++
+		c.stats.Hits++
+		c.Unlock()
+		return entry, nil
+	}
+	c.stats.Misses++
+	c.Unlock()
+	if db == nil {
+		return nil, nil
+	}
+
+	stmt, err := db.Preparex(query)
+	if err != nil {
+		return nil, err
+	}
+	entry := &cachedStmt{query: query, stmt: stmt, users: 1}
+	var evicted []*cachedStmt
+
+	c.Lock()
+	if elem, ok := c.entries[query]; ok {
+		// prepared concurrently: use the cached one
+		c.lru.MoveToFront(elem)
+		cached := elem.Value.(*cachedStmt)
+		cached.users++
+		c.Unlock()
+		_ = stmt.Close()
+		return cached, nil
+	}
+	c.entries[query] = c.lru.PushFront(entry)
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		old := c.lru.Remove(oldest).(*cachedStmt)
+		delete(c.entries, old.query)
+		c.stats.Evictions++
+		old.evicted = true
+		if old.users == 0 {
+			evicted = append(evicted, old)
+		}
+	}
+	c.Unlock()
+
+	for _, old := range evicted {
+		_ = old.stmt.Close()
+	}
+	return entry, nil
+}
+
+// release marks a statement unused, closing it if it's already evicted
+func (c *stmtCache) release(entry *cachedStmt) {
+	c.Lock()
+	entry.users--
+	closing := entry.evicted && entry.users == 0
+	c.Unlock()
+	if closing {
+		_ = entry.stmt.Close()
+	}
+}
+
+// closeAll evicts all statements
+func (c *stmtCache) closeAll() {
+	c.Lock()
+	var closing []*cachedStmt
+	for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+		entry := elem.Value.(*cachedStmt)
+		entry.evicted = true
+		if entry.users == 0 {
+			closing = append(closing, entry)
+		}
+	}
+	c.entries = map[string]*list.Element{}
+	c.lru.Init()
+	c.Unlock()
+	for _, entry := range closing {
+		_ = entry.stmt.Close()
+	}
+}
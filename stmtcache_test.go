@@ -0,0 +1,96 @@
+package dmpr
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+func TestMapper_StatementCache(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	m := &Mapper{
+		Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+		logger: logrus.New(),
+	}
+	m.StatementCache(1)
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"id", "name"}).AddRow(5, "test")
+	}
+	find := mock.ExpectPrepare(`^SELECT \* FROM example_models WHERE id = \$1$`)
+	find.ExpectQuery().WithArgs(5).WillReturnRows(rows())
+	find.ExpectQuery().WithArgs(6).WillReturnRows(rows())
+	find.WillBeClosed()
+	mock.ExpectPrepare(`^SELECT \* FROM example_models WHERE name = \$1$`).
+		ExpectQuery().WithArgs("test").WillReturnRows(rows())
+
+	for _, id := range []int64{5, 6} {
+		if err := m.Find(&ExampleModel{}, id); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.FindBy(&ExampleModel{}, "name", "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	expected := StatementCacheStats{Size: 1, Capacity: 1, Hits: 1, Misses: 2, Evictions: 1}
+	stats := m.StatementCacheStats()
+	if !reflect.DeepEqual(expected, stats) {
+		t.Errorf("stats don't match.\nExpected: %+v\nReceived: %+v", expected, stats)
+	}
+	if rate := stats.HitRate(); rate != 1.0/3 {
+		t.Errorf("unexpected hit rate: %v", rate)
+	}
+}
+
+func TestMapper_StatementCache_transaction(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+	m := &Mapper{
+		Conn:   sqlx.NewDb(db, "sqlmock"), // "sqlmock" is a magic string @ sqlmock for driver name
+		logger: logrus.New(),
+	}
+	m.StatementCache(10)
+	insert := `^INSERT INTO example_models \(name, created_at\) VALUES \(\?, NOW\(\)\) RETURNING id$`
+	mock.ExpectPrepare(insert).
+		ExpectQuery().WithArgs("first").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectQuery(insert).WithArgs("second").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2))
+	mock.ExpectQuery(`^DELETE FROM example_models RETURNING id$`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	if err := m.Create(&ExampleModel{Name: "first"}); err != nil {
+		t.Fatal(err)
+	}
+	err = m.Transaction(func(tx *Mapper) error {
+		if err := tx.Create(&ExampleModel{Name: "second"}); err != nil {
+			return err
+		}
+		rows, err := tx.Queryx("DELETE FROM example_models RETURNING id")
+		if err != nil {
+			return err
+		}
+		return rows.Close()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+	if stats := m.StatementCacheStats(); stats.Hits != 1 || stats.Misses != 2 || stats.Size != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,118 @@
+package dmpr
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/gobuffalo/flect"
+)
+
+// TableNamer is an interface models can implement to provide their own
+// table names. Table names can be schema-qualified, like "legacy.tbl_users".
+type TableNamer interface {
+	TableName() string
+}
+
+// NamingStrategy converts a model's type name into a table name.
+type NamingStrategy func(typeName string) string
+
+// DefaultNaming is the default naming strategy. It converts type names
+// to snake_cased, pluralized form.
+func DefaultNaming(typeName string) string {
+	modelName := flect.Underscore(typeName)
+	if modelName == "" {
+		return ""
+	}
+	return flect.Pluralize(modelName)
+}
+
+// tableRegistry stores table naming settings of a mapper
+type tableRegistry struct {
+	sync.RWMutex
+	naming NamingStrategy
+	tables map[reflect.Type]string
+}
+
+func (m *Mapper) registry() *tableRegistry {
+	if m.tables == nil {
+		m.tables = &tableRegistry{}
+	}
+	return m.tables
+}
+
+// Naming sets up the naming strategy for models, which are neither
+// registered nor implement TableNamer.
+func (m *Mapper) Naming(strategy NamingStrategy) {
+	reg := m.registry()
+	reg.Lock()
+	defer reg.Unlock()
+	reg.naming = strategy
+}
+
+// RegisterTable sets model's table name, overriding both TableNamer and
+// the naming strategy. Model can be a struct, pointer, or slice.
+func (m *Mapper) RegisterTable(model interface{}, name string) error {
+	t := modelType(reflect.TypeOf(model))
+	if t == nil || name == "" {
+		return ErrInvalidType
+	}
+	reg := m.registry()
+	reg.Lock()
+	defer reg.Unlock()
+	if reg.tables == nil {
+		reg.tables = map[reflect.Type]string{}
+	}
+	reg.tables[t] = name
+	return nil
+}
+
+func (m *Mapper) tableName(model interface{}) (string, error) {
+	t := modelType(reflect.TypeOf(model))
+	if t == nil {
+		return "", ErrInvalidType
+	}
+	return m.tableNameByType(t)
+}
+
+func (m *Mapper) tableNameByType(t reflect.Type) (string, error) {
+	t = deref(t)
+	if t.Name() == "" {
+		return "", ErrInvalidType
+	}
+	var naming NamingStrategy
+	if reg := m.tables; reg != nil {
+		reg.RLock()
+		name, ok := reg.tables[t]
+		naming = reg.naming
+		reg.RUnlock()
+		if ok {
+			return name, nil
+		}
+	}
+	if namer, ok := reflect.New(t).Interface().(TableNamer); ok {
+		if name := namer.TableName(); name != "" {
+			return name, nil
+		}
+	}
+	if naming == nil {
+		naming = DefaultNaming
+	}
+	name := naming(t.Name())
+	if name == "" {
+		return "", ErrInvalidType
+	}
+	return name, nil
+}
+
+// modelType returns the base type of a model, dereferencing pointers,
+// slices, and arrays.
+func modelType(t reflect.Type) reflect.Type {
+	for t != nil {
+		k := t.Kind()
+		if k != reflect.Slice && k != reflect.Array && k != reflect.Ptr {
+			break
+		}
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,79 @@
+package dmpr
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/julian7/tester"
+)
+
+type ExampleNamedModel struct {
+	ID int
+}
+
+func (ExampleNamedModel) TableName() string {
+	return "legacy.tbl_named"
+}
+
+type ExamplePerson struct {
+	ID int
+}
+
+func TestMapper_tableName(t *testing.T) {
+	tests := []struct {
+		name     string
+		model    interface{}
+		register map[interface{}]string
+		naming   NamingStrategy
+		want     string
+		err      error
+	}{
+		{
+			name:  "nil model",
+			model: nil,
+			err:   ErrInvalidType,
+		},
+		{
+			name:  "default naming",
+			model: &[]ExamplePerson{},
+			want:  "example_people",
+		},
+		{
+			name:  "table namer",
+			model: &ExampleNamedModel{},
+			want:  "legacy.tbl_named",
+		},
+		{
+			name:     "registered",
+			model:    &[]*ExampleNamedModel{},
+			register: map[interface{}]string{ExampleNamedModel{}: "legacy.tbl_users"},
+			want:     "legacy.tbl_users",
+		},
+		{
+			name:   "naming strategy",
+			model:  ExamplePerson{},
+			naming: func(name string) string { return "tbl_" + strings.ToLower(name) },
+			want:   "tbl_exampleperson",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New("")
+			for model, name := range tt.register {
+				if err := m.RegisterTable(model, name); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if tt.naming != nil {
+				m.Naming(tt.naming)
+			}
+			got, err := m.tableName(tt.model)
+			if assert := tester.AssertError(tt.err, err); assert != nil {
+				t.Error(assert)
+			}
+			if got != tt.want {
+				t.Errorf("tableName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}